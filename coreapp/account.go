@@ -0,0 +1,134 @@
+package coreapp
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/gommon/log"
+)
+
+type AccountState string
+
+const (
+	AccountStateUnregistered AccountState = "unregistered"
+	AccountStateCodeSent     AccountState = "code_sent"
+	AccountStateRegistered   AccountState = "registered"
+)
+
+// Account holds the number that is being registered (or was registered) on the mock.
+type Account struct {
+	CC          string        `json:"cc,omitempty"`
+	PhoneNumber string        `json:"phone_number,omitempty"`
+	Method      AccountMethod `json:"method,omitempty"`
+	Cert        string        `json:"cert,omitempty"`
+	Pin         string        `json:"pin,omitempty"`
+}
+
+func (a Account) WaID() string {
+	return a.CC + a.PhoneNumber
+}
+
+// registeredOnly rejects requests until the account is registered.
+func (s *Server) registeredOnly(c *gin.Context) {
+	if s.configFor(c).AccountState != AccountStateRegistered {
+		s.abortWithError(c, http.StatusForbidden, errAccountNotRegistered)
+		return
+	}
+	c.Next()
+}
+
+func (s *Server) accountHandler(c *gin.Context) {
+	var req AccountRequest
+	if err := s.bindRequest(c, &req); err != nil {
+		s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeInvalidParameter, err.Error()))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.unlock()
+
+	if s.account.Pin != "" && req.Pin != s.account.Pin {
+		s.abortWithError(c, http.StatusUnauthorized,
+			NewError(ErrorCodeAccessDenied, "Two-step verification PIN is missing or invalid"))
+		return
+	}
+
+	if s.mock.AccountState == AccountStateRegistered &&
+		s.account.CC == req.CC && s.account.PhoneNumber == req.PhoneNumber {
+		c.JSON(http.StatusCreated, AccountResponse{BaseResponse: s.baseResponseOk()})
+		return
+	}
+
+	s.account.CC = req.CC
+	s.account.PhoneNumber = req.PhoneNumber
+	s.account.Method = req.Method
+	s.account.Cert = req.Cert
+	s.mock.AccountState = AccountStateCodeSent
+
+	log.Printf("Verification code for +%s%s sent via %s: %s\n",
+		req.CC, req.PhoneNumber, req.Method, s.mock.VerificationCode)
+
+	c.JSON(http.StatusAccepted, AccountResponse{
+		BaseResponse: s.baseResponseOk(),
+		Account:      []AccountVName{{VName: "+" + req.CC + req.PhoneNumber}},
+	})
+}
+
+func (s *Server) accountVerifyHandler(c *gin.Context) {
+	var req AccountVerifyRequest
+	if err := s.bindRequest(c, &req); err != nil {
+		s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeMissingParameter, err.Error()))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.unlock()
+
+	if s.mock.AccountState != AccountStateCodeSent {
+		s.abortWithError(c, http.StatusBadRequest,
+			NewError(ErrorCodeAccessDenied, "Verification code was not requested"))
+		return
+	}
+
+	if req.Code != s.mock.VerificationCode {
+		s.abortWithError(c, http.StatusBadRequest,
+			NewError(ErrorCodeInvalidParameter, "Invalid verification code"))
+		return
+	}
+
+	s.mock.AccountState = AccountStateRegistered
+	s.updateShooter()
+	c.JSON(http.StatusCreated, s.baseResponseOk())
+}
+
+func (s *Server) setTwoStepHandler(c *gin.Context) {
+	var req TwoStepRequest
+	if err := s.bindRequest(c, &req); err != nil {
+		s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeInvalidParameter, err.Error()))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.unlock()
+
+	if s.mock.AccountState != AccountStateRegistered {
+		s.abortWithError(c, http.StatusForbidden, errAccountNotRegistered)
+		return
+	}
+
+	s.account.Pin = req.Pin
+	c.JSON(http.StatusOK, s.baseResponseOk())
+}
+
+func (s *Server) removeTwoStepHandler(c *gin.Context) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	if s.mock.AccountState != AccountStateRegistered {
+		s.abortWithError(c, http.StatusForbidden, errAccountNotRegistered)
+		return
+	}
+
+	s.account.Pin = ""
+	c.JSON(http.StatusOK, s.baseResponseOk())
+}
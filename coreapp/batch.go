@@ -0,0 +1,77 @@
+package coreapp
+
+import (
+	"sync"
+	"time"
+)
+
+// defaultBatchWindow is used when only the batch size is configured.
+const defaultBatchWindow = time.Second
+
+// WebhookBatch makes the shooter accumulate webhooks and send them as a single payload.
+type WebhookBatch struct {
+	// WindowMs is the time in milliseconds since the first webhook of the batch after which the batch is sent.
+	WindowMs int `json:"window_ms" validate:"min=0"`
+	// Size is the amount of messages, statuses and errors after which the batch is sent without waiting.
+	Size int `json:"size" validate:"min=0"`
+}
+
+func (b WebhookBatch) enabled() bool {
+	return b.WindowMs > 0 || b.Size > 1
+}
+
+func (b WebhookBatch) window() time.Duration {
+	if b.WindowMs == 0 {
+		return defaultBatchWindow
+	}
+	return time.Duration(b.WindowMs) * time.Millisecond
+}
+
+// pendingBatch is the webhook which accumulates items until the batch is sent.
+type pendingBatch struct {
+	webhook InboundWebhook
+}
+
+func (b *pendingBatch) size() int {
+	return len(b.webhook.Messages) + len(b.webhook.Statuses) + len(b.webhook.Errors)
+}
+
+type batchState struct {
+	mu      sync.Mutex
+	pending *pendingBatch
+}
+
+// enqueue adds the webhook to the pending batch. The batch is sent when it reaches the size limit
+// or when the window is over.
+func (s *Shooter) enqueue(batch WebhookBatch, webhook InboundWebhook) {
+	s.state.batch.mu.Lock()
+	pending := s.state.batch.pending
+	if pending == nil {
+		pending = &pendingBatch{}
+		s.state.batch.pending = pending
+		go func() {
+			Sleep(batch.window())
+			s.flushBatch(pending)
+		}()
+	}
+	pending.webhook = mergeWebhooks(pending.webhook, webhook)
+	full := batch.Size > 0 && pending.size() >= batch.Size
+	s.state.batch.mu.Unlock()
+
+	if full {
+		s.flushBatch(pending)
+	}
+}
+
+// flushBatch sends the batch with the current configuration if it wasn't sent yet.
+func (s *Shooter) flushBatch(pending *pendingBatch) {
+	s.state.batch.mu.Lock()
+	if s.state.batch.pending != pending {
+		s.state.batch.mu.Unlock()
+		return
+	}
+	s.state.batch.pending = nil
+	s.state.batch.mu.Unlock()
+
+	_, _ = s.dispatch(s.Config(), pending.webhook)
+}
@@ -0,0 +1,150 @@
+package coreapp
+
+import (
+	"math/rand"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// chaosHoldTime is the longest time the webhook is held for reordering or batching. It's delivered as is
+// if no other webhook is sent during this time.
+const chaosHoldTime = 3 * time.Second
+
+type ChaosAction string
+
+const (
+	ChaosDrop      ChaosAction = "drop"
+	ChaosDuplicate ChaosAction = "duplicate"
+	ChaosReorder   ChaosAction = "reorder"
+	ChaosBatch     ChaosAction = "batch"
+)
+
+// Chaos contains probabilities (from 0 to 1) of the webhook delivery misbehavior.
+type Chaos struct {
+	// Drop discards the webhook without sending it.
+	Drop float64 `json:"drop" validate:"min=0,max=1"`
+	// Duplicate sends the webhook twice.
+	Duplicate float64 `json:"duplicate" validate:"min=0,max=1"`
+	// Reorder holds the webhook and sends it after the next one, e.g. read status before the delivered one.
+	Reorder float64 `json:"reorder" validate:"min=0,max=1"`
+	// Batch holds the webhook and sends it together with the next one in the same payload.
+	Batch float64 `json:"batch" validate:"min=0,max=1"`
+}
+
+// heldWebhook is the webhook which waits for the next one to be reordered or batched with.
+type heldWebhook struct {
+	webhook InboundWebhook
+	action  ChaosAction
+}
+
+// chaosState keeps the held webhook of the shooter.
+type chaosState struct {
+	mu   sync.Mutex
+	held *heldWebhook
+}
+
+// roll returns true with provided probability.
+func roll(rnd rand.Source, probability float64) bool {
+	return probability > 0 && float64(rnd.Int63())/(1<<63) < probability
+}
+
+// chaosSource returns the source of the chaos decisions for the webhook. It's derived from the IDs of the messages
+// and statuses, so the decisions don't depend on the order of the deliveries. The shared source is used for
+// the webhooks with errors only.
+func chaosSource(webhook InboundWebhook) rand.Source {
+	var key strings.Builder
+	for _, msg := range webhook.Messages {
+		key.WriteString(msg.ID + " ")
+	}
+	for _, status := range webhook.Statuses {
+		key.WriteString(status.ID + ":" + status.Status + " ")
+	}
+	if key.Len() == 0 {
+		return src
+	}
+	for _, err := range webhook.Errors {
+		key.WriteString(strconv.Itoa(err.Code) + " ")
+	}
+	return src.derive(key.String())
+}
+
+// sendChaotic delivers the webhook applying the chaos settings. Zero code is returned if the webhook was dropped
+// or held for later delivery.
+func (s *Shooter) sendChaotic(config ShooterConfig, webhook InboundWebhook) (int, error) {
+	chaos := *config.Chaos
+	rnd := chaosSource(webhook)
+	if roll(rnd, chaos.Drop) {
+		s.logChaos(config, webhook, ChaosDrop)
+		return 0, nil
+	}
+
+	s.state.chaos.mu.Lock()
+	held := s.state.chaos.held
+	s.state.chaos.held = nil
+	if held == nil {
+		switch {
+		case roll(rnd, chaos.Reorder):
+			s.state.chaos.held = &heldWebhook{webhook: webhook, action: ChaosReorder}
+		case roll(rnd, chaos.Batch):
+			s.state.chaos.held = &heldWebhook{webhook: webhook, action: ChaosBatch}
+		}
+	}
+	hold := s.state.chaos.held
+	s.state.chaos.mu.Unlock()
+
+	if hold != nil {
+		go func() {
+			Sleep(chaosHoldTime)
+			s.releaseHeld(hold)
+		}()
+		return 0, nil
+	}
+
+	var code int
+	var err error
+	switch {
+	case held == nil:
+		code, err = s.sendWebhook(config, webhook, "")
+	case held.action == ChaosBatch:
+		code, err = s.sendWebhook(config, mergeWebhooks(held.webhook, webhook), ChaosBatch)
+	default:
+		code, err = s.sendWebhook(config, webhook, "")
+		_, _ = s.sendWebhook(config, held.webhook, ChaosReorder)
+	}
+
+	if roll(rnd, chaos.Duplicate) {
+		_, _ = s.sendWebhook(config, webhook, ChaosDuplicate)
+	}
+	return code, err
+}
+
+// releaseHeld delivers the held webhook if it wasn't taken by another delivery yet.
+func (s *Shooter) releaseHeld(held *heldWebhook) {
+	s.state.chaos.mu.Lock()
+	if s.state.chaos.held != held {
+		s.state.chaos.mu.Unlock()
+		return
+	}
+	s.state.chaos.held = nil
+	s.state.chaos.mu.Unlock()
+
+	_, _ = s.sendWebhook(s.Config(), held.webhook, "")
+}
+
+// logChaos records the webhook which was not sent to the delivery log.
+func (s *Shooter) logChaos(config ShooterConfig, webhook InboundWebhook, action ChaosAction) {
+	wh, err := config.encode(webhook)
+	if err != nil {
+		return
+	}
+
+	s.log(Delivery{
+		Time:       Now(),
+		URL:        config.Webhook,
+		Payload:    wh,
+		Recipients: webhook.recipients(),
+		Chaos:      action,
+	})
+}
@@ -0,0 +1,143 @@
+package coreapp
+
+import (
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// VirtualEpoch is the initial time of the virtual clock.
+var VirtualEpoch = time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// Clock is the time source of the mock. Virtual clock stands still until it's advanced, sleeping goroutines
+// are woken up in the order of their deadlines when the clock passes them. The clock is shared by all servers
+// of the process, including the tenants.
+type Clock struct {
+	mu      sync.Mutex
+	virtual bool
+	now     time.Time
+	timers  []*clockTimer
+}
+
+type clockTimer struct {
+	at   time.Time
+	done chan struct{}
+}
+
+var clock = &Clock{}
+
+// Now returns current time: wall clock time or the virtual one.
+func Now() time.Time {
+	return clock.Now()
+}
+
+// Sleep pauses the current goroutine. It waits for the virtual clock to be advanced if it's enabled.
+func Sleep(d time.Duration) {
+	clock.Sleep(d)
+}
+
+// UseVirtualClock replaces the wall clock with the virtual one starting at provided time.
+func UseVirtualClock(start time.Time) {
+	clock.Set(start)
+}
+
+func (c *Clock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if !c.virtual {
+		return time.Now()
+	}
+	return c.now
+}
+
+func (c *Clock) Virtual() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.virtual
+}
+
+func (c *Clock) Sleep(d time.Duration) {
+	c.mu.Lock()
+	if !c.virtual {
+		c.mu.Unlock()
+		time.Sleep(d)
+		return
+	}
+	if d <= 0 {
+		c.mu.Unlock()
+		return
+	}
+
+	timer := &clockTimer{at: c.now.Add(d), done: make(chan struct{})}
+	c.timers = append(c.timers, timer)
+	c.mu.Unlock()
+	<-timer.done
+}
+
+// Set enables the virtual clock and sets its time. Sleeping goroutines with passed deadlines are woken up.
+func (c *Clock) Set(now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.virtual = true
+	c.now = now
+
+	sort.SliceStable(c.timers, func(i, j int) bool {
+		return c.timers[i].at.Before(c.timers[j].at)
+	})
+	for len(c.timers) > 0 && !c.timers[0].at.After(now) {
+		close(c.timers[0].done)
+		c.timers = c.timers[1:]
+	}
+}
+
+// Advance moves the virtual clock forward. The virtual clock starts at the current time if it wasn't enabled.
+func (c *Clock) Advance(d time.Duration) {
+	c.Set(c.Now().Add(d))
+}
+
+type ClockRequest struct {
+	// Advance moves the clock forward, e.g. "500ms".
+	Advance string `json:"advance,omitempty"`
+	// Time sets the clock. The clock can be moved back as well.
+	Time *time.Time `json:"time,omitempty"`
+}
+
+type ClockResponse struct {
+	Time    time.Time `json:"time"`
+	Virtual bool      `json:"virtual"`
+}
+
+func (s *Server) clockHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, ClockResponse{Time: clock.Now(), Virtual: clock.Virtual()})
+}
+
+// advanceClockHandler sets or advances the virtual clock. The virtual clock is enabled by the first call.
+// The clock is moved for every tenant of the process.
+func (s *Server) advanceClockHandler(c *gin.Context) {
+	var req ClockRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var advance time.Duration
+	if req.Advance != "" {
+		var err error
+		if advance, err = time.ParseDuration(req.Advance); err != nil || advance < 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid advance duration: " + req.Advance})
+			return
+		}
+	}
+
+	if req.Time != nil {
+		clock.Set(req.Time.Add(advance))
+	} else {
+		clock.Advance(advance)
+	}
+	s.clockHandler(c)
+}
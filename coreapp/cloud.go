@@ -0,0 +1,187 @@
+package coreapp
+
+import (
+	"fmt"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/gommon/log"
+)
+
+const (
+	CloudErrorCodeInvalidParameter = 100
+	CloudErrorCodeGeneric          = 131000
+	CloudErrorCodeNotRegistered    = 133010
+)
+
+// cloudErrorCodes maps Coreapp error codes to their Cloud API counterparts.
+var cloudErrorCodes = map[int]int{
+	ErrorCodeReEngagement:       131047,
+	ErrorCodeGenericUser:        131026,
+	ErrorCodeGenericUnknown:     CloudErrorCodeGeneric,
+	ErrorCodeUnsupportedMessage: 131051,
+	ErrorCodeGeneric:            CloudErrorCodeGeneric,
+	ErrorCodeAccessDenied:       131005,
+	ErrorCodeNotFound:           CloudErrorCodeInvalidParameter,
+	ErrorCodeMissingParameter:   131008,
+	ErrorCodeInvalidParameter:   131009,
+	ErrorCodeServiceNotReady:    131016,
+	ErrorCodeInvalidUser:        131026,
+	ErrorCodeInternal:           CloudErrorCodeGeneric,
+	ErrorCodeReceiverIncapable:  131026,
+	ErrorCodeIdentityChanged:    131026,
+}
+
+func cloudErrorCode(code int) int {
+	if cloudCode, ok := cloudErrorCodes[code]; ok {
+		return cloudCode
+	}
+	return CloudErrorCodeGeneric
+}
+
+func cloudInboundErrors(errs []InboundError) []InboundError {
+	if len(errs) == 0 {
+		return errs
+	}
+
+	result := make([]InboundError, len(errs))
+	for i, err := range errs {
+		result[i] = err
+		result[i].Code = cloudErrorCode(err.Code)
+	}
+	return result
+}
+
+// cloudWebhook wraps Coreapp webhook into the Cloud API envelope.
+func cloudWebhook(webhook InboundWebhook, businessAccountID string, metadata CloudMetadata) CloudWebhook {
+	value := CloudValue{
+		MessagingProduct: "whatsapp",
+		Metadata:         metadata,
+		Contacts:         webhook.Contacts,
+		Messages:         make([]InboundMessage, len(webhook.Messages)),
+		Statuses:         make([]InboundStatus, len(webhook.Statuses)),
+		Errors:           cloudInboundErrors(webhook.Errors),
+	}
+
+	for i, msg := range webhook.Messages {
+		msg.Errors = cloudInboundErrors(msg.Errors)
+		value.Messages[i] = msg
+	}
+
+	for i, status := range webhook.Statuses {
+		status.Type = ""
+		status.Errors = cloudInboundErrors(status.Errors)
+		value.Statuses[i] = status
+	}
+
+	return CloudWebhook{
+		Object: "whatsapp_business_account",
+		Entry: []CloudEntry{{
+			ID: businessAccountID,
+			Changes: []CloudChange{{
+				Value: value,
+				Field: "messages",
+			}},
+		}},
+	}
+}
+
+func (s *Server) abortWithCloudError(c *gin.Context, status, code int, message, details string) {
+	cloudErr := CloudError{
+		Message:   fmt.Sprintf("(#%d) %s", code, message),
+		Type:      "OAuthException",
+		Code:      code,
+		FBTraceID: RandomString(23),
+	}
+	if details != "" {
+		cloudErr.ErrorData = &CloudErrorData{
+			MessagingProduct: "whatsapp",
+			Details:          details,
+		}
+	}
+	c.AbortWithStatusJSON(status, CloudErrorResponse{Error: cloudErr})
+}
+
+func (s *Server) cloudMessagesHandler(c *gin.Context) {
+	mock := s.configFor(c)
+	if phoneNumberID := c.Param("phone_number_id"); phoneNumberID != mock.PhoneNumberID {
+		s.abortWithCloudError(c, http.StatusBadRequest, CloudErrorCodeInvalidParameter,
+			fmt.Sprintf("Unsupported post request. Object with ID '%s' does not exist", phoneNumberID), "")
+		return
+	}
+
+	if mock.AccountState != AccountStateRegistered {
+		s.abortWithCloudError(c, http.StatusBadRequest, CloudErrorCodeNotRegistered,
+			"Account not registered", errAccountNotRegistered.Details)
+		return
+	}
+
+	var req CloudMessage
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("error: %s\n", err)
+		s.abortWithCloudError(c, http.StatusBadRequest, CloudErrorCodeInvalidParameter, "Invalid parameter", err.Error())
+		return
+	}
+
+	if req.RecipientType == "" {
+		req.RecipientType = RecipientIndividual
+	}
+
+	if err := validate.Struct(req); err != nil {
+		log.Printf("error: %s\n", err)
+		s.abortWithCloudError(c, http.StatusBadRequest, CloudErrorCodeInvalidParameter, "Invalid parameter", err.Error())
+		return
+	}
+
+	if req.RecipientType != RecipientIndividual {
+		s.abortWithCloudError(c, http.StatusBadRequest, CloudErrorCodeInvalidParameter,
+			"Invalid parameter", "Only individual recipients are supported")
+		return
+	}
+
+	if !mock.MessagesSuccess {
+		s.abortWithCloudError(c, http.StatusBadRequest, CloudErrorCodeGeneric, "Something went wrong", "")
+		return
+	}
+
+	input := req.To
+	req.To = NotDigitsRegex.ReplaceAllString(req.To, "")
+	messageID := MessageID(IDFormatCloud, DirectionOutbound, req.To)
+	if status, err := s.acceptMessage(mock, req.Message, messageID); err != nil {
+		s.abortWithCloudError(c, status, cloudErrorCode(err.Code), err.Title, err.Details)
+		return
+	}
+
+	c.JSON(http.StatusOK, CloudMessagesResponse{
+		MessagingProduct: "whatsapp",
+		Contacts:         []CloudContact{{Input: input, WaID: req.To}},
+		Messages:         []IDModel{{ID: messageID}},
+	})
+}
+
+// verifyWebhookHandler performs the webhook verification handshake and enables delivery if it succeeds.
+func (s *Server) verifyWebhookHandler(c *gin.Context) {
+	mock := s.config()
+	if mock.Webhook == "" || mock.VerifyToken == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "webhook and verify_token must be configured"})
+		return
+	}
+
+	if err := NewShooter(mock.Webhook, mock.WebhookHeaders).Verify(mock.VerifyToken); err != nil {
+		log.Printf("error: %s\n", err)
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.unlock()
+
+	if s.mock.Webhook != mock.Webhook || s.mock.VerifyToken != mock.VerifyToken {
+		c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "webhook configuration changed during verification"})
+		return
+	}
+
+	s.verified = true
+	s.updateShooter()
+	c.JSON(http.StatusOK, gin.H{"verified": true})
+}
@@ -0,0 +1,140 @@
+package coreapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// EnvPrefix is a prefix for the environment variables which override mock configuration.
+// Variable name is built from the JSON field name, e.g. WABA_MOCK_WEBHOOK or WABA_MOCK_MESSAGES_SUCCESS.
+const EnvPrefix = "WABA_MOCK_"
+
+// LoadMock returns the mock configuration from the file (if provided) with environment overrides applied.
+// Fields which are not present in the file or environment keep their default values.
+func LoadMock(path string) (Mock, error) {
+	mock := DefaultMock()
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return mock, err
+		}
+
+		if ext := strings.ToLower(filepath.Ext(path)); ext == ".yml" || ext == ".yaml" {
+			if data, err = yamlToJSON(data); err != nil {
+				return mock, fmt.Errorf("cannot parse %s: %w", path, err)
+			}
+		}
+
+		if err := json.Unmarshal(data, &mock); err != nil {
+			return mock, fmt.Errorf("cannot parse %s: %w", path, err)
+		}
+	}
+
+	if err := applyEnv(&mock, os.LookupEnv); err != nil {
+		return mock, err
+	}
+
+	if err := validate.Struct(mock); err != nil {
+		return mock, err
+	}
+
+	return mock, nil
+}
+
+// copyMock returns a deep copy of the configuration, so its maps and lists can be changed independently.
+func copyMock(mock Mock) (Mock, error) {
+	data, err := json.Marshal(mock)
+	if err != nil {
+		return mock, err
+	}
+
+	var copied Mock
+	if err := json.Unmarshal(data, &copied); err != nil {
+		return mock, err
+	}
+	return copied, nil
+}
+
+// applyEnv overrides fields of the struct with non-empty environment variables.
+// Strings, booleans and numbers are parsed as is, everything else must be JSON.
+func applyEnv(v interface{}, lookup func(string) (string, bool)) error {
+	rv := reflect.ValueOf(v).Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		name := strings.Split(rt.Field(i).Tag.Get("json"), ",")[0]
+		if name == "" || name == "-" {
+			continue
+		}
+
+		key := EnvPrefix + strings.ToUpper(name)
+		value, ok := lookup(key)
+		if !ok || value == "" {
+			continue
+		}
+
+		field := rv.Field(i)
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(value)
+		case reflect.Bool:
+			b, err := strconv.ParseBool(value)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", key, err)
+			}
+			field.SetBool(b)
+		case reflect.Int, reflect.Int64:
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", key, err)
+			}
+			field.SetInt(n)
+		case reflect.Float64:
+			n, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				return fmt.Errorf("invalid %s: %w", key, err)
+			}
+			field.SetFloat(n)
+		default:
+			target := reflect.New(field.Type())
+			if err := json.Unmarshal([]byte(value), target.Interface()); err != nil {
+				return fmt.Errorf("invalid %s: %w", key, err)
+			}
+			field.Set(target.Elem())
+		}
+	}
+	return nil
+}
+
+// yamlToJSON converts YAML document to JSON, so the same struct tags can be used for both formats.
+func yamlToJSON(data []byte) ([]byte, error) {
+	var v interface{}
+	if err := yaml.Unmarshal(data, &v); err != nil {
+		return nil, err
+	}
+	return json.Marshal(normalizeYAML(v))
+}
+
+func normalizeYAML(v interface{}) interface{} {
+	switch val := v.(type) {
+	case map[interface{}]interface{}:
+		result := make(map[string]interface{}, len(val))
+		for k, item := range val {
+			result[fmt.Sprint(k)] = normalizeYAML(item)
+		}
+		return result
+	case []interface{}:
+		for i, item := range val {
+			val[i] = normalizeYAML(item)
+		}
+		return val
+	default:
+		return v
+	}
+}
@@ -0,0 +1,16 @@
+package coreapp
+
+import (
+	_ "embed"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+//go:embed console/index.html
+var consolePage []byte
+
+// consoleHandler serves the web console which shows conversations as chat threads.
+func (s *Server) consoleHandler(c *gin.Context) {
+	c.Data(http.StatusOK, "text/html; charset=utf-8", consolePage)
+}
@@ -0,0 +1,196 @@
+package coreapp
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	SystemCustomerChangedNumber   = "customer_changed_number"
+	SystemCustomerIdentityChanged = "customer_identity_changed"
+)
+
+// ContactState tracks simulated changes of the customer's WhatsApp account.
+type ContactState struct {
+	// ChangedTo is a new WhatsApp ID of the customer which changed their number.
+	ChangedTo string `json:"changed_to,omitempty"`
+	// Rechecked is true when changed number was looked up via /v1/contacts after the change.
+	Rechecked bool                   `json:"rechecked,omitempty"`
+	Identity  InboundMessageIdentity `json:"identity"`
+	// IdentityPending is true until the changed identity is sent with the next message from the customer.
+	IdentityPending bool `json:"identity_pending,omitempty"`
+}
+
+// newIdentityHash returns the identity hash of the customer. It's derived from the previous hash, so the hashes
+// are reproducible with the seed and every change produces a new one.
+func newIdentityHash(waID, previous string) string {
+	b := make([]byte, 8)
+	_, _ = rand.New(src.derive(waID + previous)).Read(b)
+	return base64.StdEncoding.EncodeToString(b)
+}
+
+// contact returns state of the contact. It creates a new state if it doesn't exist. Caller must hold the lock.
+func (s *Server) contact(waID string) *ContactState {
+	contact, ok := s.contacts[waID]
+	if !ok {
+		contact = &ContactState{
+			Identity: InboundMessageIdentity{
+				Acknowledged:     "true",
+				CreatedTimestamp: Timestamp(),
+				Hash:             newIdentityHash(waID, ""),
+			},
+		}
+		s.contacts[waID] = contact
+	}
+	return contact
+}
+
+// checkRecipient returns an error if the message cannot be delivered to the contact because of the simulated changes.
+func (s *Server) checkRecipient(waID string) *Error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	contact, ok := s.contacts[waID]
+	if !ok {
+		return nil
+	}
+
+	if s.mock.BlockChangedNumbers && contact.ChangedTo != "" && !contact.Rechecked {
+		err := NewError(ErrorCodeInvalidUser, fmt.Sprintf("User changed their number to %s", contact.ChangedTo))
+		return &err
+	}
+
+	if s.mock.IdentityCheck && contact.Identity.Acknowledged != "true" {
+		err := NewError(ErrorCodeIdentityChanged, "User identity has changed and must be acknowledged")
+		return &err
+	}
+
+	return nil
+}
+
+// changedIdentity returns the changed identity of the contact if it wasn't sent with the inbound message yet.
+func (s *Server) changedIdentity(waID string) *InboundMessageIdentity {
+	s.mu.Lock()
+	defer s.unlock()
+
+	contact, ok := s.contacts[waID]
+	if !ok || !contact.IdentityPending {
+		return nil
+	}
+
+	contact.IdentityPending = false
+	identity := contact.Identity
+	return &identity
+}
+
+// resolveContact returns actual WhatsApp ID for the input and marks changed number as rechecked.
+func (s *Server) resolveContact(waID string) string {
+	s.mu.Lock()
+	defer s.unlock()
+
+	contact, ok := s.contacts[waID]
+	if !ok || contact.ChangedTo == "" {
+		return waID
+	}
+
+	contact.Rechecked = true
+	return contact.ChangedTo
+}
+
+func (s *Server) changeNumberHandler(c *gin.Context) {
+	var req ChangeNumberRequest
+	if err := s.bindRequest(c, &req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.unlock()
+
+	waID := c.Param("wa_id")
+	contact := s.contact(waID)
+	contact.ChangedTo = req.NewWaID
+	contact.Rechecked = false
+
+	s.sendSystem(s.mock, waID, "", MessageSystem{
+		Body:     fmt.Sprintf("User %s changed from %s to %s", waID, waID, req.NewWaID),
+		NewWaID:  req.NewWaID,
+		Type:     SystemCustomerChangedNumber,
+		Customer: waID,
+	})
+
+	c.JSON(http.StatusOK, contact)
+}
+
+func (s *Server) changeIdentityHandler(c *gin.Context) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	waID := c.Param("wa_id")
+	contact := s.contact(waID)
+	contact.Identity = InboundMessageIdentity{
+		Acknowledged:     "false",
+		CreatedTimestamp: Timestamp(),
+		Hash:             newIdentityHash(waID, contact.Identity.Hash),
+	}
+	contact.IdentityPending = true
+
+	s.sendSystem(s.mock, waID, "", MessageSystem{
+		Body:     fmt.Sprintf("User %s's security code has changed", waID),
+		Identity: contact.Identity.Hash,
+		Type:     SystemCustomerIdentityChanged,
+		Customer: waID,
+	})
+
+	c.JSON(http.StatusOK, contact)
+}
+
+func (s *Server) identityHandler(c *gin.Context) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	contact, ok := s.contacts[c.Param("wa_id")]
+	if !ok {
+		s.abortWithError(c, http.StatusNotFound, NewError(ErrorCodeNotFound, "Contact not found"))
+		return
+	}
+
+	identity := contact.Identity
+	created, _ := strconv.ParseInt(identity.CreatedTimestamp, 10, 64)
+	c.JSON(http.StatusOK, IdentityResponse{
+		BaseResponse: s.baseResponseOk(),
+		Identity: []IdentityInfo{{
+			Hash:             identity.Hash,
+			CreatedTimestamp: created,
+		}},
+	})
+}
+
+func (s *Server) acknowledgeIdentityHandler(c *gin.Context) {
+	var req IdentityRequest
+	if err := s.bindRequest(c, &req); err != nil {
+		s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeMissingParameter, err.Error()))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.unlock()
+
+	contact, ok := s.contacts[c.Param("wa_id")]
+	if !ok {
+		s.abortWithError(c, http.StatusNotFound, NewError(ErrorCodeNotFound, "Contact not found"))
+		return
+	}
+	if contact.Identity.Hash != req.Hash {
+		s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeInvalidParameter, "Identity hash doesn't match"))
+		return
+	}
+
+	contact.Identity.Acknowledged = "true"
+	c.JSON(http.StatusOK, s.baseResponseOk())
+}
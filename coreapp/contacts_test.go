@@ -0,0 +1,16 @@
+package coreapp
+
+import "testing"
+
+func TestIdentityHashDerived(t *testing.T) {
+	first := newIdentityHash("79001234567", "")
+	if first != newIdentityHash("79001234567", "") {
+		t.Fatal("identity hash doesn't depend only on the seed and the customer")
+	}
+	if second := newIdentityHash("79001234567", first); second == first {
+		t.Fatal("identity hash is not changed")
+	}
+	if other := newIdentityHash("79007654321", ""); other == first {
+		t.Fatal("identity hashes of different customers are equal")
+	}
+}
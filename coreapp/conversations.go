@@ -0,0 +1,48 @@
+package coreapp
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+const (
+	// conversationWindow is the lifetime of the conversation.
+	conversationWindow = 24 * time.Hour
+
+	ConversationUserInitiated     = "user_initiated"
+	ConversationBusinessInitiated = "business_initiated"
+)
+
+// conversation returns the open conversation with the customer or starts a new one. New conversation is
+// user initiated if the customer has written within the conversation window. ID of the new conversation
+// is derived from the ID of the message which started it.
+func (s *Server) conversation(waID, messageID string) InboundStatusConversation {
+	s.mu.Lock()
+	defer s.unlock()
+
+	now := Now()
+	if conversation, ok := s.conversations[waID]; ok {
+		expiration, _ := conversation.ExpirationTimestamp.Int64()
+		if now.Before(time.Unix(expiration, 0)) {
+			return conversation
+		}
+	}
+
+	origin := ConversationBusinessInitiated
+	for i := len(s.journal) - 1; i >= 0; i-- {
+		entry := s.journal[i]
+		if entry.Direction == DirectionInbound && entry.WaID == waID && now.Sub(entry.Time) < conversationWindow {
+			origin = ConversationUserInitiated
+			break
+		}
+	}
+
+	conversation := InboundStatusConversation{
+		ID:                  randomString(src.derive(messageID), 32),
+		Origin:              InboundStatusConversationOrigin{Type: origin},
+		ExpirationTimestamp: json.Number(strconv.FormatInt(now.Add(conversationWindow).Unix(), 10)),
+	}
+	s.conversations[waID] = conversation
+	return conversation
+}
@@ -0,0 +1,160 @@
+package coreaptest
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Neur0toxine/waba-coreapp-mock/coreapp"
+)
+
+// hubReceiver is the Cloud API webhook endpoint which answers the verification handshake and records
+// the payloads with their signatures.
+type hubReceiver struct {
+	*httptest.Server
+	mu         sync.Mutex
+	payloads   []json.RawMessage
+	signatures []string
+}
+
+func newHubReceiver(t *testing.T, token string) *hubReceiver {
+	r := &hubReceiver{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.Method == http.MethodGet {
+			query := req.URL.Query()
+			if query.Get("hub.mode") != "subscribe" || query.Get("hub.verify_token") != token {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			_, _ = io.WriteString(w, query.Get("hub.challenge"))
+			return
+		}
+
+		body, _ := io.ReadAll(req.Body)
+		r.mu.Lock()
+		r.payloads = append(r.payloads, body)
+		r.signatures = append(r.signatures, req.Header.Get("X-Hub-Signature-256"))
+		r.mu.Unlock()
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *hubReceiver) received() []json.RawMessage {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]json.RawMessage(nil), r.payloads...)
+}
+
+func sendCloudText(t *testing.T, ts *TestServer, to, text string) coreapp.CloudMessagesResponse {
+	t.Helper()
+	var resp coreapp.CloudMessagesResponse
+	code := post(t, ts.URL+"/v17.0/"+coreapp.DefaultMock().PhoneNumberID+"/messages", coreapp.CloudMessage{
+		MessagingProduct: "whatsapp",
+		Message: coreapp.Message{
+			To:   to,
+			Type: "text",
+			Text: &coreapp.MessageText{Body: text},
+		},
+	}, &resp)
+	if code != http.StatusOK || len(resp.Messages) != 1 {
+		t.Fatalf("unexpected response: %d %+v", code, resp)
+	}
+	return resp
+}
+
+// waitForCloudWebhook returns the first received Cloud API webhook.
+func waitForCloudWebhook(t *testing.T, payloads func() []json.RawMessage) coreapp.CloudWebhook {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, payload := range payloads() {
+			var webhook coreapp.CloudWebhook
+			if err := json.Unmarshal(payload, &webhook); err == nil && len(webhook.Entry) > 0 {
+				return webhook
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("expected webhook was not received")
+	return coreapp.CloudWebhook{}
+}
+
+func TestCloudWebhookEnvelope(t *testing.T) {
+	ts := NewTestServer(t, coreapp.Options{Cloud: true})
+	resp := sendCloudText(t, ts, "+7 900 123-45-67", "Hello")
+	if resp.MessagingProduct != "whatsapp" || len(resp.Contacts) != 1 || resp.Contacts[0].WaID != "79001234567" ||
+		resp.Contacts[0].Input != "+7 900 123-45-67" || !strings.HasPrefix(resp.Messages[0].ID, "wamid.") {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+
+	mock := coreapp.DefaultMock()
+	webhook := waitForCloudWebhook(t, ts.Received)
+	if webhook.Object != "whatsapp_business_account" || len(webhook.Entry) != 1 ||
+		webhook.Entry[0].ID != mock.BusinessAccountID || len(webhook.Entry[0].Changes) != 1 {
+		t.Fatalf("unexpected envelope: %+v", webhook)
+	}
+	change := webhook.Entry[0].Changes[0]
+	if change.Field != "messages" || change.Value.MessagingProduct != "whatsapp" ||
+		change.Value.Metadata.PhoneNumberID != mock.PhoneNumberID || change.Value.Metadata.DisplayPhoneNumber == "" {
+		t.Fatalf("unexpected change: %+v", change)
+	}
+	if statuses := change.Value.Statuses; len(statuses) != 1 || statuses[0].ID != resp.Messages[0].ID ||
+		statuses[0].RecipientID != "79001234567" {
+		t.Fatalf("unexpected statuses: %+v", statuses)
+	}
+}
+
+func TestCloudWebhookSignature(t *testing.T) {
+	r := newHubReceiver(t, "")
+	ts := NewTestServer(t, coreapp.Options{Cloud: true})
+	ts.Configure(func(mock *coreapp.Mock) {
+		mock.Webhook = r.URL
+		mock.AppSecret = "secret"
+	})
+
+	sendCloudText(t, ts, "79001234567", "Hello")
+	waitForCloudWebhook(t, r.received)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write(r.payloads[0])
+	if expected := "sha256=" + hex.EncodeToString(mac.Sum(nil)); r.signatures[0] != expected {
+		t.Fatalf("invalid signature: %s, expected %s", r.signatures[0], expected)
+	}
+}
+
+func TestCloudWebhookVerification(t *testing.T) {
+	r := newHubReceiver(t, "token")
+	ts := NewTestServer(t, coreapp.Options{Cloud: true})
+	ts.Configure(func(mock *coreapp.Mock) {
+		mock.Webhook = r.URL
+		mock.VerifyToken = "token"
+	})
+
+	sendCloudText(t, ts, "79001234567", "Hello")
+	time.Sleep(100 * time.Millisecond)
+	if len(r.received()) != 0 {
+		t.Fatal("webhook is delivered before the verification")
+	}
+
+	var result map[string]bool
+	if code := post(t, ts.URL+"/mock/webhook/verify", nil, &result); code != http.StatusOK || !result["verified"] {
+		t.Fatalf("webhook is not verified: %d %v", code, result)
+	}
+	resp := sendCloudText(t, ts, "79001234567", "Hello")
+	webhook := waitForCloudWebhook(t, r.received)
+	if statuses := webhook.Entry[0].Changes[0].Value.Statuses; len(statuses) != 1 ||
+		statuses[0].ID != resp.Messages[0].ID {
+		t.Fatalf("unexpected webhook: %+v", statuses)
+	}
+}
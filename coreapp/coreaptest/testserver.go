@@ -0,0 +1,127 @@
+// Package coreaptest runs the Coreapp mock in-process for the Go tests.
+package coreaptest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Neur0toxine/waba-coreapp-mock/coreapp"
+)
+
+// TestServer runs the mock in-process for the Go tests. Webhooks are delivered to the built-in receiver
+// unless another webhook is configured.
+type TestServer struct {
+	*httptest.Server
+	Mock *coreapp.Server
+
+	t        testing.TB
+	receiver *httptest.Server
+	mu       sync.Mutex
+	received []json.RawMessage
+}
+
+// NewTestServer starts the mock and the webhook receiver. Both are closed when the test finishes.
+func NewTestServer(t testing.TB, opts ...coreapp.Options) *TestServer {
+	t.Helper()
+
+	var options coreapp.Options
+	if len(opts) > 0 {
+		options = opts[0]
+	}
+
+	ts := &TestServer{t: t}
+	ts.receiver = httptest.NewServer(http.HandlerFunc(ts.receive))
+	ts.Mock = coreapp.NewServer(options)
+	ts.Server = httptest.NewServer(ts.Mock.Handler())
+	t.Cleanup(func() {
+		ts.Server.Close()
+		ts.receiver.Close()
+	})
+
+	ts.Configure(func(mock *coreapp.Mock) {
+		if mock.Webhook == "" {
+			mock.Webhook = ts.receiver.URL
+		}
+	})
+	return ts
+}
+
+func (ts *TestServer) receive(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	ts.mu.Lock()
+	ts.received = append(ts.received, body)
+	ts.mu.Unlock()
+	w.WriteHeader(http.StatusOK)
+}
+
+// WebhookURL returns URL of the built-in webhook receiver.
+func (ts *TestServer) WebhookURL() string {
+	return ts.receiver.URL
+}
+
+// Configure changes the mock configuration. The test fails if the resulting configuration is invalid.
+func (ts *TestServer) Configure(fn func(mock *coreapp.Mock)) {
+	ts.t.Helper()
+	if err := ts.Mock.Configure(fn); err != nil {
+		ts.t.Fatalf("invalid mock configuration: %s", err)
+	}
+}
+
+// InjectInbound delivers the message from the customer to the webhook and returns it with the generated fields.
+func (ts *TestServer) InjectInbound(msg coreapp.InboundMessage) coreapp.InboundMessage {
+	ts.t.Helper()
+	msg, code, err := ts.Mock.InjectInbound(msg)
+	if err != nil {
+		ts.t.Fatalf("cannot deliver inbound message: %s", err)
+	}
+	if code < 200 || code > 299 {
+		ts.t.Fatalf("webhook responded with code %d", code)
+	}
+	return msg
+}
+
+// Messages returns messages which were sent to the recipient. Empty recipient matches everything.
+func (ts *TestServer) Messages(to string) []coreapp.JournalEntry {
+	return ts.Mock.Journal(to, coreapp.DirectionOutbound)
+}
+
+// WaitForMessage waits until a message is sent to the recipient and returns the first one.
+// The test fails if there is no such message after the timeout.
+func (ts *TestServer) WaitForMessage(to string, timeout time.Duration) coreapp.JournalEntry {
+	ts.t.Helper()
+	deadline := time.Now().Add(timeout)
+	for {
+		if messages := ts.Messages(to); len(messages) > 0 {
+			return messages[0]
+		}
+		if time.Now().After(deadline) {
+			ts.t.Fatalf("no messages were sent to %s in %s", to, timeout)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// WebhookLog returns every webhook delivery attempt made by the mock.
+func (ts *TestServer) WebhookLog() []coreapp.Delivery {
+	return ts.Mock.Deliveries()
+}
+
+// Received returns payloads which were received by the built-in webhook receiver.
+func (ts *TestServer) Received() []json.RawMessage {
+	ts.mu.Lock()
+	defer ts.mu.Unlock()
+
+	received := make([]json.RawMessage, len(ts.received))
+	copy(received, ts.received)
+	return received
+}
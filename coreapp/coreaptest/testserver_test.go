@@ -0,0 +1,300 @@
+package coreaptest
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/Neur0toxine/waba-coreapp-mock/coreapp"
+)
+
+func post(t *testing.T, url string, body interface{}, v interface{}) int {
+	t.Helper()
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resp, err := http.Post(url, "application/json", bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+
+	if v != nil {
+		if err := json.NewDecoder(resp.Body).Decode(v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func sendText(t *testing.T, ts *TestServer, to, text string) string {
+	t.Helper()
+	var resp coreapp.MessagesResponse
+	code := post(t, ts.URL+"/v1/messages", coreapp.Message{
+		RecipientType: coreapp.RecipientIndividual,
+		To:            to,
+		Type:          "text",
+		Text:          &coreapp.MessageText{Body: text},
+	}, &resp)
+	if code != http.StatusOK || len(resp.Messages) != 1 {
+		t.Fatalf("unexpected response: %d %+v", code, resp)
+	}
+	return resp.Messages[0].ID
+}
+
+// waitForWebhook returns the first received webhook which satisfies the condition.
+func waitForWebhook(t *testing.T, ts *TestServer, cond func(coreapp.InboundWebhook) bool) coreapp.InboundWebhook {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		for _, payload := range ts.Received() {
+			var webhook coreapp.InboundWebhook
+			if err := json.Unmarshal(payload, &webhook); err == nil && cond(webhook) {
+				return webhook
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("expected webhook was not received")
+	return coreapp.InboundWebhook{}
+}
+
+func statusOf(id string) func(coreapp.InboundWebhook) bool {
+	return func(webhook coreapp.InboundWebhook) bool {
+		return len(webhook.Statuses) == 1 && webhook.Statuses[0].ID == id
+	}
+}
+
+func TestSendMessage(t *testing.T) {
+	ts := NewTestServer(t)
+	id := sendText(t, ts, "79001234567", "Hello")
+
+	msg := ts.WaitForMessage("79001234567", time.Second)
+	if msg.ID != id || msg.Message.Text == nil || msg.Message.Text.Body != "Hello" {
+		t.Fatalf("unexpected journal entry: %+v", msg)
+	}
+
+	status := waitForWebhook(t, ts, statusOf(id)).Statuses[0]
+	if status.Status != "sent" || status.RecipientID != "79001234567" {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	if len(ts.WebhookLog()) == 0 {
+		t.Fatal("webhook delivery is not logged")
+	}
+}
+
+func TestRecipientError(t *testing.T) {
+	ts := NewTestServer(t)
+	ts.Configure(func(mock *coreapp.Mock) {
+		mock.RecipientErrors = map[string]int{"79001234567": coreapp.ErrorCodeReEngagement}
+	})
+
+	id := sendText(t, ts, "79001234567", "Hello")
+	status := waitForWebhook(t, ts, statusOf(id)).Statuses[0]
+	if status.Status != "failed" || len(status.Errors) != 1 || status.Errors[0].Code != coreapp.ErrorCodeReEngagement {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+}
+
+func TestInjectInbound(t *testing.T) {
+	ts := NewTestServer(t)
+	msg := ts.InjectInbound(coreapp.InboundMessage{
+		From:    "79001234567",
+		Message: coreapp.Message{Type: "text", Text: &coreapp.MessageText{Body: "Hi"}},
+	})
+	if msg.ID == "" || msg.Timestamp == "" {
+		t.Fatalf("message fields are not generated: %+v", msg)
+	}
+
+	webhook := waitForWebhook(t, ts, func(webhook coreapp.InboundWebhook) bool {
+		return len(webhook.Messages) == 1 && webhook.Messages[0].ID == msg.ID
+	})
+	if len(webhook.Contacts) != 1 || webhook.Contacts[0].WaID != "79001234567" {
+		t.Fatalf("unexpected contacts: %+v", webhook.Contacts)
+	}
+}
+
+func TestExpectations(t *testing.T) {
+	ts := NewTestServer(t)
+	code := post(t, ts.URL+"/mock/expectations", coreapp.ExpectationRequest{
+		To:    "79001234567",
+		Text:  "Hello",
+		Count: 1,
+	}, nil)
+	if code != http.StatusCreated && code != http.StatusOK {
+		t.Fatalf("expectation is not registered: %d", code)
+	}
+
+	if result := ts.Mock.Verify(); result.Passed {
+		t.Fatal("expectation passed before the message was sent")
+	}
+
+	sendText(t, ts, "79001234567", "Hello")
+	sendText(t, ts, "79007654321", "Hello")
+	if result := ts.Mock.Verify(); !result.Passed || result.Results[0].Matched != 1 {
+		t.Fatalf("expectation failed: %+v", result)
+	}
+}
+
+func TestHeaderScenarioWebhook(t *testing.T) {
+	ts := NewTestServer(t)
+	other := NewTestServer(t)
+	ts.Configure(func(mock *coreapp.Mock) {
+		scenario, _ := json.Marshal(map[string]string{"webhook": other.WebhookURL()})
+		mock.Scenarios = map[string]json.RawMessage{"other-webhook": scenario}
+	})
+
+	data, _ := json.Marshal(coreapp.Message{
+		RecipientType: coreapp.RecipientIndividual,
+		To:            "79001234567",
+		Type:          "text",
+		Text:          &coreapp.MessageText{Body: "Hello"},
+	})
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/messages", bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(coreapp.ScenarioHeader, "other-webhook")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent coreapp.MessagesResponse
+	_ = json.NewDecoder(resp.Body).Decode(&sent)
+	resp.Body.Close()
+
+	waitForWebhook(t, other, statusOf(sent.Messages[0].ID))
+	if len(ts.Received()) != 0 {
+		t.Fatal("webhook of the scenario request was delivered to the main webhook")
+	}
+}
+
+func TestMediaDownload(t *testing.T) {
+	png := append([]byte("\x89PNG\r\n\x1a\n"), make([]byte, 100)...)
+	media := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/large.png" {
+			_, _ = w.Write(append(png, make([]byte, 5<<20)...))
+			return
+		}
+		_, _ = w.Write(png)
+	}))
+	defer media.Close()
+
+	ts := NewTestServer(t)
+	send := func(link string) string {
+		var resp coreapp.MessagesResponse
+		post(t, ts.URL+"/v1/messages", coreapp.Message{
+			RecipientType: coreapp.RecipientIndividual,
+			To:            "79001234567",
+			Type:          "image",
+			Image:         &coreapp.MessageMedia{Link: link},
+		}, &resp)
+		return resp.Messages[0].ID
+	}
+
+	id := send(media.URL + "/small.png")
+	if status := waitForWebhook(t, ts, statusOf(id)).Statuses[0]; status.Status != "sent" {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+	if msg := ts.Messages("79001234567")[0]; msg.Message.Image.MIMEType != "image/png" || msg.Message.Image.SHA256 == "" {
+		t.Fatalf("media is not saved to the journal: %+v", msg.Message.Image)
+	}
+
+	id = send(media.URL + "/large.png")
+	status := waitForWebhook(t, ts, statusOf(id)).Statuses[0]
+	if status.Status != "failed" || len(status.Errors) != 1 || status.Errors[0].Code != coreapp.ErrorCodeInternal {
+		t.Fatalf("unexpected status: %+v", status)
+	}
+}
+
+func TestIdentityChange(t *testing.T) {
+	ts := NewTestServer(t)
+	resp, err := http.Get(ts.URL + "/v1/contacts/79001234567/identity")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("identity of the unknown contact is returned: %d", resp.StatusCode)
+	}
+
+	var contact coreapp.ContactState
+	if code := post(t, ts.URL+"/mock/contacts/79001234567/identity", nil, &contact); code != http.StatusOK {
+		t.Fatalf("identity is not changed: %d", code)
+	}
+
+	inbound := func() coreapp.InboundMessage {
+		return ts.InjectInbound(coreapp.InboundMessage{
+			From:    "79001234567",
+			Message: coreapp.Message{Type: "text", Text: &coreapp.MessageText{Body: "Hi"}},
+		})
+	}
+	first, second := inbound(), inbound()
+	webhook := waitForWebhook(t, ts, func(webhook coreapp.InboundWebhook) bool {
+		return len(webhook.Messages) == 1 && webhook.Messages[0].ID == first.ID
+	})
+	identity := webhook.Messages[0].Identity
+	if identity == nil || identity.Hash != contact.Identity.Hash || identity.Acknowledged != "false" {
+		t.Fatalf("identity is not attached to the first message: %+v", identity)
+	}
+	if second.Identity != nil {
+		t.Fatalf("identity is attached to the second message: %+v", second.Identity)
+	}
+}
+
+func TestInboundMediaType(t *testing.T) {
+	ts := NewTestServer(t)
+	msg := ts.InjectInbound(coreapp.InboundMessage{
+		From:    "79001234567",
+		Message: coreapp.Message{Type: "image", Image: &coreapp.MessageMedia{MIMEType: "image/jpeg"}},
+	})
+	if msg.Image.ID == "" || msg.Image.SHA256 == "" || msg.Image.MIMEType != "image/jpeg" {
+		t.Fatalf("unexpected media: %+v", msg.Image)
+	}
+
+	msg = ts.InjectInbound(coreapp.InboundMessage{
+		From:    "79001234567",
+		Message: coreapp.Message{Type: "video"},
+	})
+	if msg.Video == nil || msg.Video.MIMEType != "video/mp4" {
+		t.Fatalf("unexpected media: %+v", msg.Video)
+	}
+}
+
+func TestScenarioKeepsRuntimeWebhook(t *testing.T) {
+	ts := NewTestServer(t)
+	ts.Configure(func(mock *coreapp.Mock) {
+		mock.Scenarios = map[string]json.RawMessage{"read": json.RawMessage(`{"messages_success_status":"read"}`)}
+	})
+
+	data, _ := json.Marshal(coreapp.Message{
+		RecipientType: coreapp.RecipientIndividual,
+		To:            "79001234567",
+		Type:          "text",
+		Text:          &coreapp.MessageText{Body: "Hello"},
+	})
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/v1/messages", bytes.NewReader(data))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(coreapp.ScenarioHeader, "read")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var sent coreapp.MessagesResponse
+	_ = json.NewDecoder(resp.Body).Decode(&sent)
+	resp.Body.Close()
+	webhook := waitForWebhook(t, ts, statusOf(sent.Messages[0].ID))
+	if webhook.Statuses[0].Status != "read" {
+		t.Fatalf("scenario is not applied: %+v", webhook.Statuses[0])
+	}
+
+	for _, scenario := range []string{"read", coreapp.DefaultScenario} {
+		if code := post(t, ts.URL+"/mock/scenarios/"+scenario, nil, nil); code != http.StatusOK {
+			t.Fatalf("cannot activate scenario %s: %d", scenario, code)
+		}
+		waitForWebhook(t, ts, statusOf(sendText(t, ts, "79001234567", "Hello")))
+	}
+}
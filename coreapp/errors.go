@@ -0,0 +1,54 @@
+package coreapp
+
+// Error codes returned by the Coreapp API.
+const (
+	ErrorCodeReEngagement       = 470
+	ErrorCodeGenericUser        = 480
+	ErrorCodeGenericUnknown     = 500
+	ErrorCodeUnsupportedMessage = 501
+	ErrorCodeGeneric            = 1000
+	ErrorCodeAccessDenied       = 1005
+	ErrorCodeNotFound           = 1006
+	ErrorCodeMissingParameter   = 1008
+	ErrorCodeInvalidParameter   = 1009
+	ErrorCodeServiceNotReady    = 1011
+	ErrorCodeInvalidUser        = 1013
+	ErrorCodeInternal           = 1014
+	ErrorCodeReceiverIncapable  = 1026
+	ErrorCodeIdentityChanged    = 1028
+)
+
+var errorTitles = map[int]string{
+	ErrorCodeReEngagement:       "Message failed to send because more than 24 hours have passed since the customer last replied to this number",
+	ErrorCodeGenericUser:        "Generic user error",
+	ErrorCodeGenericUnknown:     "Generic unknown error",
+	ErrorCodeUnsupportedMessage: "Unknown message type",
+	ErrorCodeGeneric:            "Generic error",
+	ErrorCodeAccessDenied:       "Access denied",
+	ErrorCodeNotFound:           "Resource not found",
+	ErrorCodeMissingParameter:   "Required parameter is missing",
+	ErrorCodeInvalidParameter:   "Parameter value is not valid",
+	ErrorCodeServiceNotReady:    "Service not ready",
+	ErrorCodeInvalidUser:        "User is not valid",
+	ErrorCodeInternal:           "Internal error",
+	ErrorCodeReceiverIncapable:  "Receiver Incapable",
+	ErrorCodeIdentityChanged:    "User identity changed",
+}
+
+func NewError(code int, details string) Error {
+	return Error{
+		Code:    code,
+		Title:   errorTitles[code],
+		Details: details,
+	}
+}
+
+func NewInboundError(code int, details string) InboundError {
+	return InboundError{
+		Code:    code,
+		Title:   errorTitles[code],
+		Details: details,
+	}
+}
+
+var errAccountNotRegistered = NewError(ErrorCodeAccessDenied, "Account is not registered")
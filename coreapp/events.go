@@ -0,0 +1,290 @@
+package coreapp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type EventKind string
+
+const (
+	EventRequest   EventKind = "request"
+	EventResponse  EventKind = "response"
+	EventMessageID EventKind = "message_id"
+	EventWebhook   EventKind = "webhook"
+)
+
+// maxEventBody is the maximum size of the request or response body sent to the event subscribers.
+// Recordings keep the whole body.
+const maxEventBody = 64 * 1024
+
+// Event is a single piece of traffic which passed through the mock.
+type Event struct {
+	Kind       EventKind   `json:"kind"`
+	Time       time.Time   `json:"time"`
+	Recipients []string    `json:"recipients,omitempty"`
+	Data       interface{} `json:"data"`
+}
+
+type HTTPEvent struct {
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	Status      int    `json:"status,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	// Body is the JSON body. Other bodies are kept in BodyBase64 byte for byte.
+	Body       json.RawMessage `json:"body,omitempty"`
+	BodyBase64 []byte          `json:"body_base64,omitempty"`
+}
+
+type MessageIDEvent struct {
+	ID string `json:"id"`
+	To string `json:"to"`
+}
+
+// EventBus delivers events to the subscribers. Slow subscribers miss events instead of blocking the mock.
+type EventBus struct {
+	mu   sync.RWMutex
+	subs map[chan Event]struct{}
+}
+
+func NewEventBus() *EventBus {
+	return &EventBus{subs: map[chan Event]struct{}{}}
+}
+
+func (b *EventBus) Publish(event Event) {
+	if event.Time.IsZero() {
+		event.Time = Now()
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for ch := range b.subs {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// Subscribe returns a channel with events and a function which must be called to unsubscribe.
+func (b *EventBus) Subscribe() (<-chan Event, func()) {
+	ch := make(chan Event, 100)
+	b.mu.Lock()
+	b.subs[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subs, ch)
+		b.mu.Unlock()
+	}
+}
+
+// Matches returns true if event has one of the kinds and is related to one of the recipients.
+// Empty filters match everything.
+func (e Event) Matches(kinds []EventKind, recipients []string) bool {
+	if len(kinds) > 0 {
+		found := false
+		for _, kind := range kinds {
+			found = found || kind == e.Kind
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if len(recipients) > 0 {
+		for _, recipient := range recipients {
+			if containsString(e.Recipients, recipient) {
+				return true
+			}
+		}
+		return false
+	}
+
+	return true
+}
+
+// setBody keeps JSON body as is and other bodies as base64.
+func (e *HTTPEvent) setBody(body []byte) {
+	switch {
+	case len(body) == 0:
+	case json.Valid(body):
+		e.Body = body
+	default:
+		e.BodyBase64 = body
+	}
+}
+
+// rawBody returns the body as it was sent.
+func (e HTTPEvent) rawBody() []byte {
+	if e.Body != nil {
+		return e.Body
+	}
+	return e.BodyBase64
+}
+
+// sameBody returns true if the bodies are equal. JSON bodies are compared regardless of the formatting.
+func (e HTTPEvent) sameBody(other HTTPEvent) bool {
+	return bytes.Equal(canonicalJSON(e.Body), canonicalJSON(other.Body)) && bytes.Equal(e.BodyBase64, other.BodyBase64)
+}
+
+// preview returns the event for the subscribers. Non-JSON bodies are encoded as a string, bodies over
+// maxEventBody are truncated.
+func (e HTTPEvent) preview() HTTPEvent {
+	body := e.rawBody()
+	if e.BodyBase64 == nil && len(body) <= maxEventBody {
+		return e
+	}
+
+	if len(body) > maxEventBody {
+		body = body[:maxEventBody]
+	}
+	e.Body, _ = json.Marshal(string(body))
+	e.BodyBase64 = nil
+	return e
+}
+
+// bodyRecipients extracts recipients from the API request or response body.
+func bodyRecipients(body []byte) []string {
+	var payload struct {
+		To       string    `json:"to"`
+		Contacts []Contact `json:"contacts"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return nil
+	}
+
+	var recipients []string
+	if payload.To != "" {
+		recipients = append(recipients, NotDigitsRegex.ReplaceAllString(payload.To, ""))
+	}
+	for _, contact := range payload.Contacts {
+		if contact.WaID != "" && !containsString(recipients, contact.WaID) {
+			recipients = append(recipients, contact.WaID)
+		}
+	}
+	return recipients
+}
+
+type captureWriter struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+	// limit is the maximum size of the captured body, zero means no limit.
+	limit int
+}
+
+func (w *captureWriter) Write(b []byte) (int, error) {
+	if w.limit == 0 || w.body.Len() < w.limit {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *captureWriter) WriteString(s string) (int, error) {
+	if w.limit == 0 || w.body.Len() < w.limit {
+		w.body.WriteString(s)
+	}
+	return w.ResponseWriter.WriteString(s)
+}
+
+// capture publishes request and response events for every API call and records them if recording is enabled.
+func (s *Server) capture(c *gin.Context) {
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	started := Now()
+	recipients := bodyRecipients(body)
+	request := HTTPEvent{
+		Method:      c.Request.Method,
+		Path:        c.Request.URL.RequestURI(),
+		ContentType: c.GetHeader("Content-Type"),
+	}
+	request.setBody(body)
+	s.events.Publish(Event{
+		Kind:       EventRequest,
+		Time:       started,
+		Recipients: recipients,
+		Data:       request.preview(),
+	})
+
+	// The whole response body is captured only for the recording, subscribers get the preview.
+	writer := &captureWriter{ResponseWriter: c.Writer}
+	if s.recorder == nil {
+		writer.limit = maxEventBody
+	}
+	c.Writer = writer
+	c.Next()
+
+	for _, recipient := range bodyRecipients(writer.body.Bytes()) {
+		if !containsString(recipients, recipient) {
+			recipients = append(recipients, recipient)
+		}
+	}
+	response := HTTPEvent{
+		Method:      c.Request.Method,
+		Path:        c.Request.URL.RequestURI(),
+		Status:      writer.Status(),
+		ContentType: writer.Header().Get("Content-Type"),
+	}
+	response.setBody(writer.body.Bytes())
+	s.events.Publish(Event{
+		Kind:       EventResponse,
+		Recipients: recipients,
+		Data:       response.preview(),
+	})
+
+	if s.recorder != nil {
+		s.recorder.Record(Record{
+			Time:     started,
+			Kind:     RecordAPI,
+			Request:  &request,
+			Response: &response,
+		})
+	}
+}
+
+func splitFilter(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, ",")
+}
+
+// eventsHandler streams events as Server-Sent Events. Events can be filtered with comma-separated
+// "recipient" and "kind" query parameters.
+func (s *Server) eventsHandler(c *gin.Context) {
+	recipients := splitFilter(c.Query("recipient"))
+	var kinds []EventKind
+	for _, kind := range splitFilter(c.Query("kind")) {
+		kinds = append(kinds, EventKind(kind))
+	}
+
+	events, unsubscribe := s.events.Subscribe()
+	defer unsubscribe()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("X-Accel-Buffering", "no")
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-c.Request.Context().Done():
+			return false
+		case event := <-events:
+			if event.Matches(kinds, recipients) {
+				c.SSEvent(string(event.Kind), event)
+			}
+			return true
+		}
+	})
+}
@@ -0,0 +1,228 @@
+package coreapp
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ExpectationMode string
+
+const (
+	ExpectExactly ExpectationMode = "exactly"
+	ExpectAtLeast ExpectationMode = "at_least"
+	ExpectAtMost  ExpectationMode = "at_most"
+)
+
+type ExpectationStatus string
+
+const (
+	ExpectationPassed  ExpectationStatus = "passed"
+	ExpectationFailed  ExpectationStatus = "failed"
+	ExpectationPending ExpectationStatus = "pending"
+)
+
+// ExpectationRequest describes outbound messages which must be sent. Empty filters match every message.
+type ExpectationRequest struct {
+	To           string          `json:"to,omitempty"`
+	Type         MessageType     `json:"type,omitempty"`
+	TemplateName string          `json:"template_name,omitempty"`
+	Text         string          `json:"text,omitempty"`
+	Count        int             `json:"count" validate:"min=0"`
+	Mode         ExpectationMode `json:"mode,omitempty" validate:"omitempty,oneof=exactly at_least at_most"`
+	// Within limits the time since the expectation registration, e.g. "10s". Empty value means no limit.
+	Within string `json:"within,omitempty"`
+}
+
+type Expectation struct {
+	ID         string             `json:"id"`
+	Registered time.Time          `json:"registered"`
+	Request    ExpectationRequest `json:"request"`
+}
+
+type ExpectationResult struct {
+	Expectation Expectation       `json:"expectation"`
+	Status      ExpectationStatus `json:"status"`
+	Expected    string            `json:"expected"`
+	Matched     int               `json:"matched"`
+	// Actual contains every message to the recipient in the expectation time window.
+	Actual []MessageSummary `json:"actual"`
+}
+
+type MessageSummary struct {
+	ID           string      `json:"id"`
+	To           string      `json:"to"`
+	Type         MessageType `json:"type"`
+	TemplateName string      `json:"template_name,omitempty"`
+	Text         string      `json:"text,omitempty"`
+	Time         time.Time   `json:"time"`
+	Matched      bool        `json:"matched"`
+}
+
+type VerificationResult struct {
+	Passed  bool                `json:"passed"`
+	Results []ExpectationResult `json:"results"`
+}
+
+// window returns the time limit of the expectation. Within is validated on registration.
+func (e Expectation) window() time.Duration {
+	window, _ := time.ParseDuration(e.Request.Within)
+	return window
+}
+
+func (e Expectation) String() string {
+	mode := e.Request.Mode
+	if mode == "" {
+		mode = ExpectExactly
+	}
+
+	s := fmt.Sprintf("%s %d", mode, e.Request.Count)
+	if e.Request.Type != "" {
+		s += " " + string(e.Request.Type)
+	}
+	s += " messages"
+	if e.Request.TemplateName != "" {
+		s += fmt.Sprintf(" with template %s", e.Request.TemplateName)
+	}
+	if e.Request.Text != "" {
+		s += fmt.Sprintf(" with text %q", e.Request.Text)
+	}
+	if e.Request.To != "" {
+		s += " to " + e.Request.To
+	}
+	if e.Request.Within != "" {
+		s += " within " + e.Request.Within
+	}
+	return s
+}
+
+func (e Expectation) matches(entry JournalEntry) bool {
+	msg := entry.Message
+	if e.Request.Type != "" && msg.Type != e.Request.Type {
+		return false
+	}
+	if e.Request.TemplateName != "" && (msg.Template == nil || msg.Template.Name != e.Request.TemplateName) {
+		return false
+	}
+	if e.Request.Text != "" && (msg.Text == nil || msg.Text.Body != e.Request.Text) {
+		return false
+	}
+	return true
+}
+
+// inWindow returns true if the entry was sent after the expectation registration and within its time limit.
+func (e Expectation) inWindow(entry JournalEntry) bool {
+	if entry.Time.Before(e.Registered) {
+		return false
+	}
+	return e.window() == 0 || !entry.Time.After(e.Registered.Add(e.window()))
+}
+
+func (e Expectation) verify(journal []JournalEntry, now time.Time) ExpectationResult {
+	result := ExpectationResult{
+		Expectation: e,
+		Expected:    e.String(),
+		Actual:      []MessageSummary{},
+	}
+
+	for _, entry := range journal {
+		if entry.Direction != DirectionOutbound || !e.inWindow(entry) ||
+			(e.Request.To != "" && entry.WaID != e.Request.To) {
+			continue
+		}
+
+		summary := MessageSummary{
+			ID:      entry.ID,
+			To:      entry.WaID,
+			Type:    entry.Message.Type,
+			Time:    entry.Time,
+			Matched: e.matches(entry),
+		}
+		if entry.Message.Template != nil {
+			summary.TemplateName = entry.Message.Template.Name
+		}
+		if entry.Message.Text != nil {
+			summary.Text = entry.Message.Text.Body
+		}
+		if summary.Matched {
+			result.Matched++
+		}
+		result.Actual = append(result.Actual, summary)
+	}
+
+	var ok, final bool
+	switch e.Request.Mode {
+	case ExpectAtLeast:
+		ok, final = result.Matched >= e.Request.Count, result.Matched >= e.Request.Count
+	case ExpectAtMost:
+		ok, final = result.Matched <= e.Request.Count, result.Matched > e.Request.Count
+	default:
+		ok, final = result.Matched == e.Request.Count, result.Matched > e.Request.Count
+	}
+
+	open := e.window() == 0 || now.Before(e.Registered.Add(e.window()))
+	switch {
+	case ok:
+		result.Status = ExpectationPassed
+	case open && !final:
+		result.Status = ExpectationPending
+	default:
+		result.Status = ExpectationFailed
+	}
+	return result
+}
+
+func (s *Server) addExpectationHandler(c *gin.Context) {
+	var req ExpectationRequest
+	if err := s.bindRequest(c, &req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	expectation := Expectation{
+		ID:         RandomString(16),
+		Registered: Now(),
+		Request:    req,
+	}
+	if req.Within != "" {
+		if window, err := time.ParseDuration(req.Within); err != nil || window <= 0 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid within duration: " + req.Within})
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.unlock()
+
+	s.expectations = append(s.expectations, expectation)
+	c.JSON(http.StatusCreated, expectation)
+}
+
+func (s *Server) clearExpectationsHandler(c *gin.Context) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	s.expectations = nil
+	c.Status(http.StatusNoContent)
+}
+
+// Verify checks every registered expectation against the journal.
+func (s *Server) Verify() VerificationResult {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := Now()
+	result := VerificationResult{Passed: true, Results: []ExpectationResult{}}
+	for _, expectation := range s.expectations {
+		res := expectation.verify(s.journal, now)
+		result.Passed = result.Passed && res.Status == ExpectationPassed
+		result.Results = append(result.Results, res)
+	}
+	return result
+}
+
+func (s *Server) verifyExpectationsHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, s.Verify())
+}
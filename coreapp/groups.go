@@ -0,0 +1,310 @@
+package coreapp
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	SystemGroupParticipantAdd    = "group_participant_add"
+	SystemGroupParticipantRemove = "group_participant_remove"
+)
+
+func (s *Server) newInviteLink() string {
+	return "https://chat.whatsapp.com/" + RandomString(22)
+}
+
+// group returns the group from the request path. It aborts the request if the group doesn't exist.
+// Caller must hold the lock.
+func (s *Server) group(c *gin.Context) *GroupInfo {
+	group, ok := s.groups[c.Param("id")]
+	if !ok {
+		s.abortWithError(c, http.StatusNotFound, NewError(ErrorCodeNotFound, "Group not found"))
+		return nil
+	}
+	return group
+}
+
+func (s *Server) createGroupHandler(c *gin.Context) {
+	var req GroupRequest
+	if err := s.bindRequest(c, &req); err != nil {
+		s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeInvalidParameter, err.Error()))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.unlock()
+
+	creator := s.account.WaID()
+	created := Now().Unix()
+	id := fmt.Sprintf("%s-%d", creator, created)
+	for _, exists := s.groups[id]; exists; _, exists = s.groups[id] {
+		created++
+		id = fmt.Sprintf("%s-%d", creator, created)
+	}
+
+	s.groups[id] = &GroupInfo{
+		ID:           id,
+		CreationTime: created,
+		Creator:      creator,
+		Subject:      req.Subject,
+		Admins:       []string{creator},
+		Participants: []string{creator},
+		Link:         s.newInviteLink(),
+	}
+
+	c.JSON(http.StatusCreated, GroupsResponse{
+		BaseResponse: s.baseResponseOk(),
+		Groups:       []GroupInfo{{ID: id, CreationTime: created}},
+	})
+}
+
+func (s *Server) groupsHandler(c *gin.Context) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	groups := make([]GroupInfo, 0, len(s.groups))
+	for id := range s.groups {
+		groups = append(groups, GroupInfo{ID: id})
+	}
+	sort.Slice(groups, func(i, j int) bool {
+		return groups[i].ID < groups[j].ID
+	})
+
+	c.JSON(http.StatusOK, GroupsResponse{
+		BaseResponse: s.baseResponseOk(),
+		Groups:       groups,
+	})
+}
+
+func (s *Server) groupHandler(c *gin.Context) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	group := s.group(c)
+	if group == nil {
+		return
+	}
+
+	info := *group
+	info.ID = ""
+	info.Link = ""
+	c.JSON(http.StatusOK, GroupsResponse{
+		BaseResponse: s.baseResponseOk(),
+		Groups:       []GroupInfo{info},
+	})
+}
+
+func (s *Server) updateGroupHandler(c *gin.Context) {
+	var req GroupRequest
+	if err := s.bindRequest(c, &req); err != nil {
+		s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeInvalidParameter, err.Error()))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.unlock()
+
+	group := s.group(c)
+	if group == nil {
+		return
+	}
+
+	group.Subject = req.Subject
+	c.JSON(http.StatusOK, s.baseResponseOk())
+}
+
+func (s *Server) groupInviteHandler(c *gin.Context) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	group := s.group(c)
+	if group == nil {
+		return
+	}
+
+	c.JSON(http.StatusOK, GroupsResponse{
+		BaseResponse: s.baseResponseOk(),
+		Groups:       []GroupInfo{{Link: group.Link}},
+	})
+}
+
+func (s *Server) revokeGroupInviteHandler(c *gin.Context) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	group := s.group(c)
+	if group == nil {
+		return
+	}
+
+	group.Link = s.newInviteLink()
+	c.JSON(http.StatusOK, s.baseResponseOk())
+}
+
+// addGroupParticipantsHandler simulates users joining the group via the invite link.
+// Real Coreapp doesn't allow adding participants directly.
+func (s *Server) addGroupParticipantsHandler(c *gin.Context) {
+	var req GroupParticipantsRequest
+	if err := s.bindRequest(c, &req); err != nil {
+		s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeInvalidParameter, err.Error()))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.unlock()
+
+	group := s.group(c)
+	if group == nil {
+		return
+	}
+
+	var added []string
+	for _, waID := range req.WaIDs {
+		if !containsString(group.Participants, waID) {
+			group.Participants = append(group.Participants, waID)
+			added = append(added, waID)
+		}
+	}
+
+	if len(added) > 0 {
+		s.sendSystem(s.contextMock(c), group.Creator, group.ID, MessageSystem{
+			Body: fmt.Sprintf("+%s joined using this group's invite link", strings.Join(added, ", +")),
+			Type: SystemGroupParticipantAdd,
+		})
+	}
+
+	c.JSON(http.StatusOK, s.baseResponseOk())
+}
+
+func (s *Server) removeGroupParticipantsHandler(c *gin.Context) {
+	var req GroupParticipantsRequest
+	if err := s.bindRequest(c, &req); err != nil {
+		s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeInvalidParameter, err.Error()))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.unlock()
+
+	group := s.group(c)
+	if group == nil {
+		return
+	}
+
+	for _, waID := range req.WaIDs {
+		if waID == group.Creator {
+			s.abortWithError(c, http.StatusBadRequest,
+				NewError(ErrorCodeInvalidParameter, "Group creator cannot be removed, leave the group instead"))
+			return
+		}
+		if !containsString(group.Participants, waID) {
+			s.abortWithError(c, http.StatusNotFound,
+				NewError(ErrorCodeNotFound, fmt.Sprintf("%s is not a participant of the group", waID)))
+			return
+		}
+	}
+
+	for _, waID := range req.WaIDs {
+		group.Participants = removeString(group.Participants, waID)
+		group.Admins = removeString(group.Admins, waID)
+	}
+
+	s.sendSystem(s.contextMock(c), group.Creator, group.ID, MessageSystem{
+		Body: fmt.Sprintf("+%s removed +%s", group.Creator, strings.Join(req.WaIDs, ", +")),
+		Type: SystemGroupParticipantRemove,
+	})
+
+	c.JSON(http.StatusOK, s.baseResponseOk())
+}
+
+func (s *Server) addGroupAdminsHandler(c *gin.Context) {
+	var req GroupParticipantsRequest
+	if err := s.bindRequest(c, &req); err != nil {
+		s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeInvalidParameter, err.Error()))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.unlock()
+
+	group := s.group(c)
+	if group == nil {
+		return
+	}
+
+	for _, waID := range req.WaIDs {
+		if !containsString(group.Participants, waID) {
+			s.abortWithError(c, http.StatusNotFound,
+				NewError(ErrorCodeNotFound, fmt.Sprintf("%s is not a participant of the group", waID)))
+			return
+		}
+	}
+
+	for _, waID := range req.WaIDs {
+		if !containsString(group.Admins, waID) {
+			group.Admins = append(group.Admins, waID)
+		}
+	}
+
+	c.JSON(http.StatusOK, s.baseResponseOk())
+}
+
+func (s *Server) removeGroupAdminsHandler(c *gin.Context) {
+	var req GroupParticipantsRequest
+	if err := s.bindRequest(c, &req); err != nil {
+		s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeInvalidParameter, err.Error()))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.unlock()
+
+	group := s.group(c)
+	if group == nil {
+		return
+	}
+
+	for _, waID := range req.WaIDs {
+		if waID != group.Creator {
+			group.Admins = removeString(group.Admins, waID)
+		}
+	}
+
+	c.JSON(http.StatusOK, s.baseResponseOk())
+}
+
+func (s *Server) leaveGroupHandler(c *gin.Context) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	if group := s.group(c); group == nil {
+		return
+	}
+
+	delete(s.groups, c.Param("id"))
+	c.JSON(http.StatusOK, s.baseResponseOk())
+}
+
+func containsString(items []string, item string) bool {
+	for _, v := range items {
+		if v == item {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(items []string, item string) []string {
+	result := items[:0]
+	for _, v := range items {
+		if v != item {
+			result = append(result, v)
+		}
+	}
+	return result
+}
@@ -0,0 +1,33 @@
+package coreapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestGroupCreatorIsParticipant(t *testing.T) {
+	s := NewServer(Options{})
+
+	w := serve(s, http.MethodPost, "/v1/groups", `{"subject": "Support"}`)
+	var created GroupsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &created); err != nil || w.Code != http.StatusCreated {
+		t.Fatalf("group is not created: %d %s", w.Code, w.Body)
+	}
+
+	id := created.Groups[0].ID
+	w = serve(s, http.MethodGet, "/v1/groups/"+id, "")
+	var group GroupsResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &group); err != nil {
+		t.Fatal(err)
+	}
+	creator := group.Groups[0].Creator
+	if participants := group.Groups[0].Participants; len(participants) != 1 || participants[0] != creator {
+		t.Fatalf("creator is not a participant: %v", participants)
+	}
+
+	w = serve(s, http.MethodDelete, "/v1/groups/"+id+"/participants", `{"wa_ids": ["`+creator+`"]}`)
+	if w.Code != http.StatusBadRequest {
+		t.Fatalf("creator is removed: %d", w.Code)
+	}
+}
@@ -0,0 +1,159 @@
+package coreapp
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/gommon/log"
+)
+
+const MessageTypeUnknown MessageType = "unknown"
+
+var ErrNoWebhook = errors.New("webhook is not configured")
+
+// injectInboundHandler delivers the provided message to the webhook as if it was sent by the customer.
+// Messages with "unknown" type are delivered with unsupported message type error unless errors are provided.
+func (s *Server) injectInboundHandler(c *gin.Context) {
+	var msg InboundMessage
+	if err := c.ShouldBindJSON(&msg); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if msg.From == "" {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "from is required"})
+		return
+	}
+
+	msg, code, err := s.InjectInbound(msg)
+	if errors.Is(err, ErrNoWebhook) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("error: %s\n", err)
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("inbound webhook code: %d\n", code)
+
+	c.JSON(http.StatusOK, gin.H{"message": msg, "webhook_code": code})
+}
+
+// InjectInbound delivers the message from the customer to the webhook and records it in the journal.
+// Missing message ID and timestamp are generated, the changed identity of the customer is attached to the first
+// message after the change. It returns the delivered message and the webhook response code.
+func (s *Server) InjectInbound(msg InboundMessage) (InboundMessage, int, error) {
+	if !s.config().hasWebhooks() {
+		return msg, 0, ErrNoWebhook
+	}
+
+	if msg.ID == "" {
+		msg.ID = MessageID(inboundIDFormat(s.cloud), DirectionInbound, msg.From)
+	}
+
+	if msg.Timestamp == "" {
+		msg.Timestamp = Timestamp()
+	}
+
+	if msg.Identity == nil {
+		msg.Identity = s.changedIdentity(msg.From)
+	}
+
+	s.attachInboundMedia(&msg)
+
+	if msg.Type == MessageTypeUnknown && len(msg.Errors) == 0 {
+		msg.Errors = []InboundError{
+			NewInboundError(ErrorCodeUnsupportedMessage, "Message type is not currently supported"),
+		}
+	}
+
+	s.record(DirectionInbound, msg.From, msg)
+	code, err := s.shooter.SendMessage(msg)
+	return msg, code, err
+}
+
+// inboundMediaField returns the field with the media of the inbound message.
+func inboundMediaField(msg *InboundMessage) **MessageMedia {
+	switch msg.Type {
+	case "audio":
+		return &msg.Audio
+	case "document":
+		return &msg.Document
+	case "image":
+		return &msg.Image
+	case "sticker":
+		return &msg.Sticker
+	case "video":
+		return &msg.Video
+	case "voice":
+		return &msg.Voice
+	}
+	return nil
+}
+
+// attachInboundMedia makes the media of the inbound message downloadable via /v1/media/{id}. Media which
+// references uploaded file gets its checksum, a placeholder file is generated for any other media. MIME type
+// of the file is used unless it's provided in the message.
+func (s *Server) attachInboundMedia(msg *InboundMessage) {
+	field := inboundMediaField(msg)
+	if field == nil {
+		return
+	}
+	if *field == nil {
+		*field = &MessageMedia{}
+	}
+	media := *field
+
+	file, ok := s.mediaFile(media.ID)
+	if !ok {
+		data, mimeType, filename := placeholderMedia(msg.Type)
+		file = newMediaFile(data, mimeType)
+		if media.ID != "" {
+			file.ID = media.ID
+		}
+		if msg.Type == "document" && media.Filename == "" {
+			media.Filename = filename
+		}
+
+		s.mu.Lock()
+		s.media[file.ID] = file
+		s.unlock()
+	}
+
+	media.ID, media.SHA256 = file.ID, file.SHA256
+	if media.MIMEType == "" {
+		media.MIMEType = file.MIMEType
+	}
+}
+
+// injectErrorsHandler delivers webhook with the provided errors. Missing titles are filled for known error codes.
+func (s *Server) injectErrorsHandler(c *gin.Context) {
+	var errs []InboundError
+	if err := c.ShouldBindJSON(&errs); err != nil || len(errs) == 0 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "at least one error is required"})
+		return
+	}
+
+	if !s.config().hasWebhooks() {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": ErrNoWebhook.Error()})
+		return
+	}
+
+	for i, e := range errs {
+		if e.Title == "" {
+			errs[i].Title = errorTitles[e.Code]
+		}
+	}
+
+	code, err := s.shooter.SendErrors(errs...)
+	if err != nil {
+		log.Printf("error: %s\n", err)
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+	log.Printf("errors webhook code: %d\n", code)
+
+	c.JSON(http.StatusOK, gin.H{"errors": errs, "webhook_code": code})
+}
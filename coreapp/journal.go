@@ -0,0 +1,162 @@
+package coreapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/gommon/log"
+)
+
+type Direction string
+
+const (
+	DirectionOutbound Direction = "outbound"
+	DirectionInbound  Direction = "inbound"
+)
+
+// JournalEntry is a message which was sent via the API or delivered to the webhook on behalf of the customer.
+type JournalEntry struct {
+	ID        string         `json:"id"`
+	Direction Direction      `json:"direction"`
+	WaID      string         `json:"wa_id"`
+	Time      time.Time      `json:"time"`
+	Message   InboundMessage `json:"message"`
+	Status    string         `json:"status,omitempty"`
+}
+
+// record appends the message to the journal.
+func (s *Server) record(direction Direction, waID string, msg InboundMessage) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	s.journal = append(s.journal, JournalEntry{
+		ID:        msg.ID,
+		Direction: direction,
+		WaID:      waID,
+		Time:      Now(),
+		Message:   msg,
+	})
+}
+
+// recordStatus updates status of the outbound message in the journal.
+func (s *Server) recordStatus(id, status string) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	for i := len(s.journal) - 1; i >= 0; i-- {
+		if s.journal[i].ID == id {
+			s.journal[i].Status = status
+			return
+		}
+	}
+}
+
+// recordMedia replaces the media of the message in the journal.
+func (s *Server) recordMedia(id string, media *MessageMedia) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	for i := len(s.journal) - 1; i >= 0; i-- {
+		if s.journal[i].ID == id {
+			if field := inboundMediaField(&s.journal[i].Message); field != nil {
+				*field = media
+			}
+			return
+		}
+	}
+}
+
+// journalEntry returns the message with the provided ID.
+func (s *Server) journalEntry(id string) (JournalEntry, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for i := len(s.journal) - 1; i >= 0; i-- {
+		if s.journal[i].ID == id {
+			return s.journal[i], true
+		}
+	}
+	return JournalEntry{}, false
+}
+
+// Journal returns recorded messages filtered by WhatsApp ID and direction. Empty filters match everything.
+func (s *Server) Journal(waID string, direction Direction) []JournalEntry {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	entries := []JournalEntry{}
+	for _, entry := range s.journal {
+		if (waID == "" || entry.WaID == waID) && (direction == "" || entry.Direction == direction) {
+			entries = append(entries, entry)
+		}
+	}
+	return entries
+}
+
+func (s *Server) journalHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, s.Journal(c.Query("wa_id"), Direction(c.Query("direction"))))
+}
+
+func (s *Server) clearJournalHandler(c *gin.Context) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	s.journal = nil
+	c.Status(http.StatusNoContent)
+}
+
+// messageStatusHandler delivers the status of the outbound message to the webhook.
+// Failed status is delivered with generic unknown error unless error code is provided.
+func (s *Server) messageStatusHandler(c *gin.Context) {
+	var req MessageStatusRequest
+	if err := s.bindRequest(c, &req); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	entry, ok := s.journalEntry(c.Param("id"))
+	if !ok || entry.Direction != DirectionOutbound {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": "message not found"})
+		return
+	}
+
+	if !s.config().hasWebhooks() {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": ErrNoWebhook.Error()})
+		return
+	}
+
+	status := InboundStatus{
+		Type:        "message",
+		ID:          entry.ID,
+		RecipientID: entry.WaID,
+		Status:      req.Status,
+		Timestamp:   json.Number(Timestamp()),
+	}
+	if req.Status == "failed" {
+		if req.ErrorCode == 0 {
+			req.ErrorCode = ErrorCodeGenericUnknown
+		}
+		status.Errors = []InboundError{NewInboundError(req.ErrorCode, "")}
+	}
+
+	s.recordStatus(entry.ID, status.Status)
+	code, err := s.shooter.SendStatus(status)
+	if err != nil {
+		log.Printf("error: %s\n", err)
+		c.AbortWithStatusJSON(http.StatusBadGateway, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"status": status, "webhook_code": code})
+}
+
+// Deliveries returns the log of the last webhook deliveries.
+func (s *Server) Deliveries() []Delivery {
+	return s.shooter.Deliveries()
+}
+
+func (s *Server) webhooksHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, s.Deliveries())
+}
@@ -0,0 +1,192 @@
+package coreapp
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+const MediaProviderTypeWWW = "www"
+
+// mediaProviders returns configured media providers. Caller must hold the lock.
+func (s *Server) mediaProviders() []MediaProvider {
+	if s.settings.Media == nil {
+		return []MediaProvider{}
+	}
+	return s.settings.Media.Providers
+}
+
+// mediaProvider returns the media provider with the provided name.
+func (s *Server) mediaProvider(name string) (MediaProvider, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, provider := range s.mediaProviders() {
+		if provider.Name == name {
+			return provider, true
+		}
+	}
+	return MediaProvider{}, false
+}
+
+func (s *Server) mediaProvidersHandler(c *gin.Context) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	settings := s.applicationSettings()
+	settings.Media = &MediaSettings{Providers: s.mediaProviders()}
+	c.JSON(http.StatusOK, SettingsResponse{
+		BaseResponse: s.baseResponseOk(),
+		Settings:     Settings{Application: &settings},
+	})
+}
+
+// updateMediaProvidersHandler creates the providers or replaces the existing ones with the same name.
+func (s *Server) updateMediaProvidersHandler(c *gin.Context) {
+	var req []MediaProvider
+	if err := c.ShouldBindJSON(&req); err != nil || len(req) == 0 {
+		s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeMissingParameter, "At least one provider is required"))
+		return
+	}
+
+	for _, provider := range req {
+		if provider.Name == "" {
+			s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeMissingParameter, "Provider name is required"))
+			return
+		}
+		if provider.Type != MediaProviderTypeWWW {
+			s.abortWithError(c, http.StatusBadRequest,
+				NewError(ErrorCodeInvalidParameter, fmt.Sprintf("Unsupported provider type: %s", provider.Type)))
+			return
+		}
+		if provider.Config != nil && provider.Config.Basic != nil && provider.Config.Basic.Username == "" {
+			s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeMissingParameter, "Basic auth username is required"))
+			return
+		}
+	}
+
+	s.mu.Lock()
+	defer s.unlock()
+
+	providers := append([]MediaProvider{}, s.mediaProviders()...)
+	for _, provider := range req {
+		replaced := false
+		for i := range providers {
+			if providers[i].Name == provider.Name {
+				providers[i], replaced = provider, true
+			}
+		}
+		if !replaced {
+			providers = append(providers, provider)
+		}
+	}
+	s.settings.Media = &MediaSettings{Providers: providers}
+	c.JSON(http.StatusOK, s.baseResponseOk())
+}
+
+func (s *Server) deleteMediaProviderHandler(c *gin.Context) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	providers := []MediaProvider{}
+	for _, provider := range s.mediaProviders() {
+		if provider.Name != c.Param("name") {
+			providers = append(providers, provider)
+		}
+	}
+	if len(providers) == len(s.mediaProviders()) {
+		s.abortWithError(c, http.StatusNotFound, NewError(ErrorCodeNotFound, "Media provider not found"))
+		return
+	}
+
+	s.settings.Media = &MediaSettings{Providers: providers}
+	c.JSON(http.StatusOK, s.baseResponseOk())
+}
+
+// messageMedia returns the media object of the media message.
+func messageMedia(msg Message) *MessageMedia {
+	switch msg.Type {
+	case "audio":
+		return msg.Audio
+	case "document":
+		return msg.Document
+	case "image":
+		return msg.Image
+	case "sticker":
+		return msg.Sticker
+	case "video":
+		return msg.Video
+	}
+	return nil
+}
+
+// fetchMedia downloads the media link using credentials of the media provider. Provider config from the message
+// is used as is, otherwise the provider is looked up by name in the application settings. Media larger than maxBytes
+// is not downloaded completely.
+func (s *Server) fetchMedia(media *MessageMedia, maxBytes int) ([]byte, string, error) {
+	req, err := http.NewRequest(http.MethodGet, media.Link, nil)
+	if err != nil {
+		return nil, "", err
+	}
+
+	if media.Provider != nil {
+		provider := *media.Provider
+		if provider.Config == nil {
+			var ok bool
+			if provider, ok = s.mediaProvider(provider.Name); !ok {
+				return nil, "", fmt.Errorf("media provider %s not found", media.Provider.Name)
+			}
+		}
+
+		if provider.Config != nil {
+			switch {
+			case provider.Config.Bearer != "":
+				req.Header.Set("Authorization", "Bearer "+provider.Config.Bearer)
+			case provider.Config.Basic != nil:
+				req.SetBasicAuth(provider.Config.Basic.Username, provider.Config.Basic.Password)
+			}
+		}
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, "", fmt.Errorf("media server responded with code %d", resp.StatusCode)
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, int64(maxBytes)+1))
+	if err != nil {
+		return nil, "", err
+	}
+	if len(body) > maxBytes {
+		return nil, "", fmt.Errorf("media size exceeds %d bytes limit", maxBytes)
+	}
+	return body, resp.Header.Get("Content-Type"), nil
+}
+
+// downloadMedia fetches and validates the media link of the outbound message. MIME type and checksum
+// of the downloaded media are saved to the journal entry of the message.
+func (s *Server) downloadMedia(msgID string, msgType MessageType, media *MessageMedia) *InboundError {
+	data, contentType, err := s.fetchMedia(media, mediaLimits[msgType].MaxBytes)
+	if err != nil {
+		inboundErr := NewInboundError(ErrorCodeInternal, "Media download error: "+err.Error())
+		return &inboundErr
+	}
+
+	mimeType := SniffMIMEType(data, contentType)
+	if err := validateMedia(msgType, mimeType, data); err != nil {
+		inboundErr := NewInboundError(err.Code, err.Details)
+		return &inboundErr
+	}
+
+	downloaded := *media
+	downloaded.MIMEType, downloaded.SHA256 = mimeType, checksum(data)
+	s.recordMedia(msgID, &downloaded)
+	return nil
+}
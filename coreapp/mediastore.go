@@ -0,0 +1,219 @@
+package coreapp
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	stickerSize      = 512
+	maxDocumentBytes = 100 << 20
+)
+
+type mediaLimit struct {
+	MIMETypes []string
+	MaxBytes  int
+}
+
+// mediaLimits contains allowed MIME types and sizes for every media message type. Any type is allowed for documents.
+var mediaLimits = map[MessageType]mediaLimit{
+	"image":    {MIMETypes: []string{"image/jpeg", "image/png"}, MaxBytes: 5 << 20},
+	"sticker":  {MIMETypes: []string{"image/webp"}, MaxBytes: 100 << 10},
+	"audio":    {MIMETypes: []string{"audio/aac", "audio/mp4", "audio/amr", "audio/mpeg", "audio/ogg"}, MaxBytes: 16 << 20},
+	"video":    {MIMETypes: []string{"video/mp4", "video/3gpp"}, MaxBytes: 16 << 20},
+	"document": {MaxBytes: maxDocumentBytes},
+}
+
+// MediaFile is the media uploaded to the mock. The data is saved apart from the state, see mediaName.
+type MediaFile struct {
+	ID       string `json:"id"`
+	MIMEType string `json:"mime_type"`
+	SHA256   string `json:"sha256"`
+	Data     []byte `json:"-"`
+}
+
+func newMediaFile(data []byte, mimeType string) MediaFile {
+	return MediaFile{
+		ID:       RandomUUID(),
+		MIMEType: mimeType,
+		SHA256:   checksum(data),
+		Data:     data,
+	}
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// SniffMIMEType detects MIME type of the media. Declared type is used if the content is not recognized.
+func SniffMIMEType(data []byte, declared string) string {
+	declared = strings.TrimSpace(strings.Split(declared, ";")[0])
+	switch {
+	case len(data) >= 12 && string(data[4:8]) == "ftyp":
+		switch brand := string(data[8:12]); {
+		case brand == "M4A ":
+			return "audio/mp4"
+		case strings.HasPrefix(brand, "3g"):
+			return "video/3gpp"
+		default:
+			return "video/mp4"
+		}
+	case bytes.HasPrefix(data, []byte("#!AMR")):
+		return "audio/amr"
+	case len(data) >= 2 && data[0] == 0xff && data[1]&0xf6 == 0xf0:
+		return "audio/aac"
+	case len(data) >= 2 && data[0] == 0xff && data[1]&0xe0 == 0xe0:
+		return "audio/mpeg"
+	case bytes.HasPrefix(data, []byte("OggS")):
+		return "audio/ogg"
+	}
+
+	detected := strings.Split(http.DetectContentType(data), ";")[0]
+	if (detected == "application/octet-stream" || detected == "text/plain") && declared != "" {
+		return declared
+	}
+	return detected
+}
+
+// mediaType returns the message type which the media of provided MIME type is sent with by default.
+func mediaType(mimeType string) MessageType {
+	for msgType, limit := range mediaLimits {
+		if containsString(limit.MIMETypes, mimeType) {
+			return msgType
+		}
+	}
+	return "document"
+}
+
+// webpSize returns dimensions of the WebP image.
+func webpSize(data []byte) (width, height int, ok bool) {
+	if len(data) < 30 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 0, 0, false
+	}
+
+	switch string(data[12:16]) {
+	case "VP8 ":
+		return int(binary.LittleEndian.Uint16(data[26:28]) & 0x3fff),
+			int(binary.LittleEndian.Uint16(data[28:30]) & 0x3fff), true
+	case "VP8L":
+		bits := binary.LittleEndian.Uint32(data[21:25])
+		return int(bits&0x3fff) + 1, int(bits>>14&0x3fff) + 1, true
+	case "VP8X":
+		return int(uint32(data[24])|uint32(data[25])<<8|uint32(data[26])<<16) + 1,
+			int(uint32(data[27])|uint32(data[28])<<8|uint32(data[29])<<16) + 1, true
+	}
+	return 0, 0, false
+}
+
+// validateMedia checks that the media can be sent as the message of provided type.
+func validateMedia(msgType MessageType, mimeType string, data []byte) *Error {
+	limit, ok := mediaLimits[msgType]
+	if !ok {
+		return nil
+	}
+
+	if len(limit.MIMETypes) > 0 && !containsString(limit.MIMETypes, mimeType) {
+		err := NewError(ErrorCodeInvalidParameter, fmt.Sprintf("Media type %s is not supported for %s", mimeType, msgType))
+		return &err
+	}
+
+	if len(data) > limit.MaxBytes {
+		err := NewError(ErrorCodeInvalidParameter,
+			fmt.Sprintf("Media size %d exceeds %d bytes limit for %s", len(data), limit.MaxBytes, msgType))
+		return &err
+	}
+
+	if mimeType == "audio/ogg" && !bytes.Contains(data[:min(len(data), 64)], []byte("OpusHead")) {
+		err := NewError(ErrorCodeInvalidParameter, "Only OGG files with Opus codec are supported")
+		return &err
+	}
+
+	if msgType == "sticker" {
+		width, height, ok := webpSize(data)
+		if !ok || width != stickerSize || height != stickerSize {
+			err := NewError(ErrorCodeInvalidParameter,
+				fmt.Sprintf("Sticker must be %dx%d pixels, got %dx%d", stickerSize, stickerSize, width, height))
+			return &err
+		}
+	}
+
+	return nil
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+// mediaFile returns the uploaded media.
+func (s *Server) mediaFile(id string) (MediaFile, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	file, ok := s.media[id]
+	return file, ok
+}
+
+// uploadMediaHandler stores media from the request body. Media is validated against the limits of the message type
+// it would be sent with by default.
+func (s *Server) uploadMediaHandler(c *gin.Context) {
+	data, err := io.ReadAll(io.LimitReader(c.Request.Body, maxDocumentBytes+1))
+	if err != nil {
+		s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeInvalidParameter, err.Error()))
+		return
+	}
+	if len(data) == 0 {
+		s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeMissingParameter, "Media file is required"))
+		return
+	}
+
+	mimeType := SniffMIMEType(data, c.ContentType())
+	if err := validateMedia(mediaType(mimeType), mimeType, data); err != nil {
+		s.abortWithError(c, http.StatusBadRequest, *err)
+		return
+	}
+
+	file := newMediaFile(data, mimeType)
+	s.mu.Lock()
+	s.media[file.ID] = file
+	s.unlock()
+
+	c.JSON(http.StatusCreated, MediaResponse{
+		BaseResponse: s.baseResponseOk(),
+		Media:        []IDModel{{ID: file.ID}},
+	})
+}
+
+func (s *Server) downloadMediaHandler(c *gin.Context) {
+	file, ok := s.mediaFile(c.Param("id"))
+	if !ok {
+		s.abortWithError(c, http.StatusNotFound, NewError(ErrorCodeNotFound, "Media not found"))
+		return
+	}
+
+	c.Data(http.StatusOK, file.MIMEType, file.Data)
+}
+
+func (s *Server) deleteMediaHandler(c *gin.Context) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	if _, ok := s.media[c.Param("id")]; !ok {
+		s.abortWithError(c, http.StatusNotFound, NewError(ErrorCodeNotFound, "Media not found"))
+		return
+	}
+
+	delete(s.media, c.Param("id"))
+	c.JSON(http.StatusOK, s.baseResponseOk())
+}
@@ -0,0 +1,118 @@
+package coreapp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"sync/atomic"
+)
+
+type IDFormat string
+
+const (
+	// IDFormatCoreapp is base64 of the direction, BCD encoded WhatsApp ID and unique bytes, e.g. gBEGkXmJQZVSAgkJhUU...
+	IDFormatCoreapp IDFormat = "coreapp"
+	// IDFormatCloud is "wamid." and base64 of the Thrift-encoded WhatsApp ID, direction and hex ID, e.g. wamid.HBgL...
+	IDFormatCloud IDFormat = "cloud"
+)
+
+// messageCounter makes message IDs unique within the process.
+var messageCounter uint32
+
+// uniqueBytes returns n bytes which end with the scrambled message counter. Random bytes are used for the rest.
+// Multiplication by the odd number is a bijection, so scrambled counters are still unique.
+func uniqueBytes(n int) []byte {
+	b := make([]byte, n)
+	for i := 0; i < n-4; i += 7 {
+		v := src.Int63()
+		for j := i; j < i+7 && j < n-4; j++ {
+			b[j] = byte(v)
+			v >>= 8
+		}
+	}
+	binary.BigEndian.PutUint32(b[n-4:], atomic.AddUint32(&messageCounter, 1)*0x9e3779b1)
+	return b
+}
+
+// bcd encodes the digits two per byte. Odd number of digits is padded with 0xF.
+func bcd(digits string) []byte {
+	digits = NotDigitsRegex.ReplaceAllString(digits, "")
+	if len(digits)%2 == 1 {
+		digits += "?"
+	}
+
+	b := make([]byte, len(digits)/2)
+	for i := range b {
+		hi, lo := digits[i*2]-'0', digits[i*2+1]-'0'
+		if digits[i*2+1] == '?' {
+			lo = 0xf
+		}
+		b[i] = hi<<4 | lo
+	}
+	return b
+}
+
+// MessageID generates message ID in the format of the API. Outbound messages are sent to the WhatsApp ID,
+// inbound ones are sent by it.
+func MessageID(format IDFormat, direction Direction, waID string) string {
+	outbound := direction == DirectionOutbound
+	if format == IDFormatCloud {
+		return cloudMessageID(outbound, waID)
+	}
+
+	phone := bcd(waID)
+	size := byte(len(phone))
+	if len(NotDigitsRegex.ReplaceAllString(waID, ""))%2 == 1 {
+		size |= 0x80
+	}
+
+	var buf bytes.Buffer
+	if outbound {
+		buf.WriteByte(0x80)
+	} else {
+		buf.WriteByte(0x00)
+	}
+	buf.Write([]byte{0x11, size})
+	buf.Write(phone)
+
+	unique := uniqueBytes(10)
+	if outbound {
+		unique = unique[1:]
+	}
+	buf.Write([]byte{0x02, byte(len(unique))})
+	buf.Write(unique)
+	return base64.RawStdEncoding.EncodeToString(buf.Bytes())
+}
+
+func cloudMessageID(outbound bool, waID string) string {
+	waID = NotDigitsRegex.ReplaceAllString(waID, "")
+
+	// Thrift compact struct: WhatsApp ID, i32 field, then nested struct with the direction and the hex ID.
+	var buf bytes.Buffer
+	buf.Write([]byte{0x1c, 0x18, byte(len(waID))})
+	buf.WriteString(waID)
+	buf.Write([]byte{0x15, 0x02, 0x00})
+
+	unique := uniqueBytes(10)
+	if outbound {
+		buf.WriteByte(0x11)
+		unique = unique[1:]
+	} else {
+		buf.WriteByte(0x12)
+	}
+	id := strings.ToUpper(hex.EncodeToString(unique))
+	buf.Write([]byte{0x18, byte(len(id))})
+	buf.WriteString(id)
+	buf.WriteByte(0x00)
+	return "wamid." + base64.StdEncoding.EncodeToString(buf.Bytes())
+}
+
+// inboundIDFormat returns format of the IDs of the messages delivered to the webhook.
+func inboundIDFormat(cloud bool) IDFormat {
+	if cloud {
+		return IDFormatCloud
+	}
+	return IDFormatCoreapp
+}
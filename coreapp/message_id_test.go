@@ -0,0 +1,61 @@
+package coreapp
+
+import (
+	"bytes"
+	"encoding/base64"
+	"strings"
+	"testing"
+)
+
+func TestMessageIDCoreapp(t *testing.T) {
+	tests := []struct {
+		direction Direction
+		waID      string
+		prefix    string
+		header    []byte
+	}{
+		{DirectionOutbound, "79001234567", "gBGG", []byte{0x80, 0x11, 0x86, 0x79, 0x00, 0x12, 0x34, 0x56, 0x7f}},
+		{DirectionInbound, "79001234567", "ABGG", []byte{0x00, 0x11, 0x86, 0x79, 0x00, 0x12, 0x34, 0x56, 0x7f}},
+		{DirectionOutbound, "+7 900 123-45-67-8", "gBEG", []byte{0x80, 0x11, 0x06, 0x79, 0x00, 0x12, 0x34, 0x56, 0x78}},
+	}
+	for _, test := range tests {
+		id := MessageID(IDFormatCoreapp, test.direction, test.waID)
+		if !strings.HasPrefix(id, test.prefix) {
+			t.Fatalf("%s doesn't start with %s", id, test.prefix)
+		}
+		data, err := base64.RawStdEncoding.DecodeString(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.HasPrefix(data, test.header) {
+			t.Fatalf("unexpected header of %s: % x", id, data)
+		}
+	}
+}
+
+func TestMessageIDCloud(t *testing.T) {
+	id := MessageID(IDFormatCloud, DirectionOutbound, "79001234567")
+	if !strings.HasPrefix(id, "wamid.HBgL") {
+		t.Fatalf("%s doesn't start with wamid.HBgL", id)
+	}
+	data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(id, "wamid."))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(data, []byte("79001234567")) {
+		t.Fatalf("%s doesn't contain WhatsApp ID", id)
+	}
+}
+
+func TestMessageIDUnique(t *testing.T) {
+	ids := map[string]bool{}
+	for i := 0; i < 10000; i++ {
+		for _, format := range []IDFormat{IDFormatCoreapp, IDFormatCloud} {
+			id := MessageID(format, DirectionOutbound, "79001234567")
+			if ids[id] {
+				t.Fatalf("duplicate message ID %s", id)
+			}
+			ids[id] = true
+		}
+	}
+}
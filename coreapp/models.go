@@ -1,4 +1,4 @@
-package main
+package coreapp
 
 import "encoding/json"
 
@@ -19,6 +19,7 @@ type (
 	InteractiveMessageType   string
 	InteractiveHeaderType    string
 	InteractiveButtonType    string
+	AccountMethod            string
 )
 
 const (
@@ -56,6 +57,16 @@ const (
 	ContactURLWork ContactURLType = "WORK"
 )
 
+const (
+	RecipientIndividual RecipientType = "individual"
+	RecipientGroup      RecipientType = "group"
+)
+
+const (
+	AccountMethodSMS   AccountMethod = "sms"
+	AccountMethodVoice AccountMethod = "voice"
+)
+
 type BaseResponse struct {
 	Meta   *Metadata `json:"meta,omitempty"`
 	Errors []Error   `json:"errors,omitempty"`
@@ -94,8 +105,8 @@ type Contact struct {
 }
 
 type Message struct {
-	RecipientType RecipientType       `json:"recipient_type,omitempty"  validate:"required,eq=individual"`
-	To            string              `json:"to" validate:"required,min=1"`
+	RecipientType RecipientType       `json:"recipient_type,omitempty"  validate:"required,oneof=individual group"`
+	To            string              `json:"to,omitempty" validate:"required,min=1"`
 	Type          MessageType         `json:"type,omitempty" validate:"required,oneof=audio contact document image location sticker template text voice video interactive button"`
 	Preview       bool                `json:"preview,omitempty"`
 	Text          *MessageText        `json:"text,omitempty"`
@@ -115,6 +126,7 @@ type InboundMessage struct {
 	Button    *InboundMessageButton   `json:"button,omitempty"`
 	Context   *InboundMessageContext  `json:"context,omitempty"`
 	From      string                  `json:"from,omitempty"`
+	GroupID   string                  `json:"group_id,omitempty"`
 	ID        string                  `json:"id,omitempty"`
 	Identity  *InboundMessageIdentity `json:"identity,omitempty"`
 	Timestamp string                  `json:"timestamp,omitempty"`
@@ -164,6 +176,7 @@ type InboundStatus struct {
 	Status       string                     `json:"status,omitempty"`
 	Timestamp    json.Number                `json:"timestamp,omitempty"`
 	Type         string                     `json:"type,omitempty"`
+	Errors       []InboundError             `json:"errors,omitempty"`
 }
 
 type InboundStatusPricing struct {
@@ -202,6 +215,7 @@ type MessageSystem struct {
 	NewWaID  string `json:"new_wa_id,omitempty"`
 	Type     string `json:"type,omitempty"`
 	Identity string `json:"identity,omitempty"`
+	Customer string `json:"customer,omitempty"`
 }
 
 type Profile struct {
@@ -213,6 +227,11 @@ type MessagesResponse struct {
 	Messages []IDModel `json:"messages,omitempty"`
 }
 
+type MediaResponse struct {
+	BaseResponse
+	Media []IDModel `json:"media,omitempty"`
+}
+
 type IDModel struct {
 	ID string `json:"id,omitempty"`
 }
@@ -389,3 +408,187 @@ type InteractiveAction struct {
 	Sections          []InteractiveSection `json:"sections,omitempty"`
 	ProductRetailerID string               `json:"product_retailer_id,omitempty"`
 }
+
+type AccountRequest struct {
+	CC          string        `json:"cc" validate:"required,numeric"`
+	PhoneNumber string        `json:"phone_number" validate:"required,numeric"`
+	Method      AccountMethod `json:"method" validate:"required,oneof=sms voice"`
+	Cert        string        `json:"cert" validate:"required,base64"`
+	Pin         string        `json:"pin,omitempty" validate:"omitempty,len=6,numeric"`
+}
+
+type AccountResponse struct {
+	BaseResponse
+	Account []AccountVName `json:"account,omitempty"`
+}
+
+type AccountVName struct {
+	VName string `json:"vname"`
+}
+
+type AccountVerifyRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+type TwoStepRequest struct {
+	Pin string `json:"pin" validate:"required,len=6,numeric"`
+}
+
+type GroupRequest struct {
+	Subject string `json:"subject" validate:"required,max=25"`
+}
+
+type GroupParticipantsRequest struct {
+	WaIDs []string `json:"wa_ids" validate:"required,min=1,dive,required"`
+}
+
+type GroupsResponse struct {
+	BaseResponse
+	Groups []GroupInfo `json:"groups,omitempty"`
+}
+
+type GroupInfo struct {
+	ID           string   `json:"id,omitempty"`
+	CreationTime int64    `json:"creation_time,omitempty"`
+	Creator      string   `json:"creator,omitempty"`
+	Subject      string   `json:"subject,omitempty"`
+	Admins       []string `json:"admins,omitempty"`
+	Participants []string `json:"participants,omitempty"`
+	Link         string   `json:"link,omitempty"`
+}
+
+type ChangeNumberRequest struct {
+	NewWaID string `json:"new_wa_id" validate:"required,numeric"`
+}
+
+type MessageStatusRequest struct {
+	Status    string `json:"status" validate:"required,oneof=sent delivered read failed deleted"`
+	ErrorCode int    `json:"error_code,omitempty"`
+}
+
+type IdentityRequest struct {
+	Hash string `json:"hash" validate:"required"`
+}
+
+type IdentityResponse struct {
+	BaseResponse
+	Identity []IdentityInfo `json:"identity,omitempty"`
+}
+
+type IdentityInfo struct {
+	Hash             string `json:"hash"`
+	CreatedTimestamp int64  `json:"created_timestamp"`
+}
+
+type CloudMessage struct {
+	MessagingProduct string `json:"messaging_product" validate:"required,eq=whatsapp"`
+	Message
+}
+
+type CloudMessagesResponse struct {
+	MessagingProduct string         `json:"messaging_product"`
+	Contacts         []CloudContact `json:"contacts"`
+	Messages         []IDModel      `json:"messages"`
+}
+
+type CloudContact struct {
+	Input string `json:"input"`
+	WaID  string `json:"wa_id"`
+}
+
+type CloudErrorResponse struct {
+	Error CloudError `json:"error"`
+}
+
+type CloudError struct {
+	Message   string          `json:"message"`
+	Type      string          `json:"type"`
+	Code      int             `json:"code"`
+	ErrorData *CloudErrorData `json:"error_data,omitempty"`
+	FBTraceID string          `json:"fbtrace_id"`
+}
+
+type CloudErrorData struct {
+	MessagingProduct string `json:"messaging_product"`
+	Details          string `json:"details"`
+}
+
+type CloudWebhook struct {
+	Object string       `json:"object"`
+	Entry  []CloudEntry `json:"entry"`
+}
+
+type CloudEntry struct {
+	ID      string        `json:"id"`
+	Changes []CloudChange `json:"changes"`
+}
+
+type CloudChange struct {
+	Value CloudValue `json:"value"`
+	Field string     `json:"field"`
+}
+
+type CloudValue struct {
+	MessagingProduct string           `json:"messaging_product"`
+	Metadata         CloudMetadata    `json:"metadata"`
+	Contacts         []InboundContact `json:"contacts,omitempty"`
+	Messages         []InboundMessage `json:"messages,omitempty"`
+	Statuses         []InboundStatus  `json:"statuses,omitempty"`
+	Errors           []InboundError   `json:"errors,omitempty"`
+}
+
+type CloudMetadata struct {
+	DisplayPhoneNumber string `json:"display_phone_number"`
+	PhoneNumberID      string `json:"phone_number_id"`
+}
+
+type ApplicationSettings struct {
+	CallbackPersist           bool              `json:"callback_persist"`
+	CallbackBackoffDelayMS    int               `json:"callback_backoff_delay_ms,omitempty"`
+	MaxCallbackBackoffDelayMS int               `json:"max_callback_backoff_delay_ms,omitempty"`
+	SentStatus                bool              `json:"sent_status"`
+	UnhealthyInterval         int               `json:"unhealthy_interval,omitempty"`
+	Webhooks                  *WebhooksSettings `json:"webhooks,omitempty"`
+	Media                     *MediaSettings    `json:"media,omitempty"`
+}
+
+type MediaSettings struct {
+	Providers []MediaProvider `json:"providers"`
+}
+
+type WebhooksSettings struct {
+	URL                   string `json:"url"`
+	MaxConcurrentRequests int    `json:"max_concurrent_requests,omitempty"`
+}
+
+type BusinessProfile struct {
+	Address     string   `json:"address,omitempty" validate:"max=256"`
+	Description string   `json:"description,omitempty" validate:"max=256"`
+	Email       string   `json:"email,omitempty" validate:"omitempty,email,max=128"`
+	Vertical    string   `json:"vertical,omitempty" validate:"max=128"`
+	Websites    []string `json:"websites,omitempty" validate:"max=2,dive,url"`
+}
+
+type SettingsResponse struct {
+	BaseResponse
+	Settings Settings `json:"settings"`
+}
+
+type Settings struct {
+	Application *ApplicationSettings `json:"application,omitempty"`
+	Business    *BusinessSettings    `json:"business,omitempty"`
+	Data        string               `json:"data,omitempty"`
+}
+
+type BusinessSettings struct {
+	Profile BusinessProfile `json:"profile"`
+}
+
+type BackupRequest struct {
+	Password string `json:"password" validate:"required"`
+}
+
+type RestoreRequest struct {
+	Password string `json:"password" validate:"required"`
+	Data     string `json:"data" validate:"required,base64"`
+}
@@ -0,0 +1,282 @@
+package coreapp
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// placeholderMedia generates a small valid file which can be sent as the media message of provided type.
+func placeholderMedia(msgType MessageType) (data []byte, mimeType, filename string) {
+	switch msgType {
+	case "image":
+		return placeholderPNG(), "image/png", "placeholder.png"
+	case "audio", "voice":
+		return placeholderOpus(), "audio/ogg", "placeholder.ogg"
+	case "sticker":
+		return placeholderWebP(), "image/webp", "placeholder.webp"
+	case "video":
+		return placeholderMP4(), "video/mp4", "placeholder.mp4"
+	default:
+		return placeholderPDF(), "application/pdf", "placeholder.pdf"
+	}
+}
+
+func placeholderPNG() []byte {
+	img := image.NewRGBA(image.Rect(0, 0, 64, 64))
+	for x := 0; x < 64; x++ {
+		for y := 0; y < 64; y++ {
+			img.Set(x, y, color.RGBA{R: 37, G: 211, B: 102, A: 255})
+		}
+	}
+
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	return buf.Bytes()
+}
+
+// placeholderWebP returns 512x512 lossless WebP sticker filled with a single color.
+func placeholderWebP() []byte {
+	bits := &bitWriter{}
+	bits.write(0x2f, 8)
+	bits.write(stickerSize-1, 14)
+	bits.write(stickerSize-1, 14)
+	// No alpha, version 0, no transforms, no color cache and no meta prefix codes.
+	bits.write(0, 1+3+1+1+1)
+	// Green, red, blue and alpha prefix codes contain a single 8-bit symbol, so pixels take no bits at all.
+	for _, value := range []uint32{211, 37, 102, 255} {
+		bits.write(1, 1)
+		bits.write(0, 1)
+		bits.write(1, 1)
+		bits.write(value, 8)
+	}
+	// Distance prefix code with a single 1-bit symbol.
+	bits.write(1, 1)
+	bits.write(0, 1)
+	bits.write(0, 1)
+	bits.write(0, 1)
+
+	return riff("WEBP", chunk("VP8L", bits.bytes()))
+}
+
+// placeholderOpus returns one second of silence in the OGG Opus container.
+func placeholderOpus() []byte {
+	const serial = 0x4d4f434b
+
+	var head bytes.Buffer
+	head.WriteString("OpusHead")
+	head.Write([]byte{1, 1})
+	_ = binary.Write(&head, binary.LittleEndian, uint16(312))
+	_ = binary.Write(&head, binary.LittleEndian, uint32(48000))
+	_ = binary.Write(&head, binary.LittleEndian, uint16(0))
+	head.WriteByte(0)
+
+	vendor := "waba-coreapp-mock"
+	var tags bytes.Buffer
+	tags.WriteString("OpusTags")
+	_ = binary.Write(&tags, binary.LittleEndian, uint32(len(vendor)))
+	tags.WriteString(vendor)
+	_ = binary.Write(&tags, binary.LittleEndian, uint32(0))
+
+	// 50 frames of 20ms silence.
+	var packets [][]byte
+	for i := 0; i < 50; i++ {
+		packets = append(packets, []byte{0xf8, 0xff, 0xfe})
+	}
+
+	var buf bytes.Buffer
+	buf.Write(oggPage(serial, 0, 0x02, 0, [][]byte{head.Bytes()}))
+	buf.Write(oggPage(serial, 1, 0x00, 0, [][]byte{tags.Bytes()}))
+	buf.Write(oggPage(serial, 2, 0x04, 312+48000, packets))
+	return buf.Bytes()
+}
+
+// placeholderMP4 returns MP4 file with a single 64x64 video track. The track has no samples, so players show
+// an empty video of zero duration.
+func placeholderMP4() []byte {
+	var ftyp bytes.Buffer
+	ftyp.WriteString("isom")
+	_ = binary.Write(&ftyp, binary.BigEndian, uint32(512))
+	ftyp.WriteString("isomiso2mp41")
+
+	matrix := []uint32{0x00010000, 0, 0, 0, 0x00010000, 0, 0, 0, 0x40000000}
+
+	mvhd := make([]byte, 100)
+	binary.BigEndian.PutUint32(mvhd[12:16], 1000)
+	binary.BigEndian.PutUint32(mvhd[20:24], 0x00010000)
+	binary.BigEndian.PutUint16(mvhd[24:26], 0x0100)
+	for i, v := range matrix {
+		binary.BigEndian.PutUint32(mvhd[36+i*4:], v)
+	}
+	binary.BigEndian.PutUint32(mvhd[96:100], 2)
+
+	// Track is enabled and used in the presentation.
+	tkhd := make([]byte, 84)
+	binary.BigEndian.PutUint32(tkhd[0:4], 0x00000003)
+	binary.BigEndian.PutUint32(tkhd[12:16], 1)
+	for i, v := range matrix {
+		binary.BigEndian.PutUint32(tkhd[40+i*4:], v)
+	}
+	binary.BigEndian.PutUint32(tkhd[76:80], 64<<16)
+	binary.BigEndian.PutUint32(tkhd[80:84], 64<<16)
+
+	mdhd := make([]byte, 24)
+	binary.BigEndian.PutUint32(mdhd[12:16], 1000)
+	// Undetermined language, packed ISO-639-2/T code "und".
+	binary.BigEndian.PutUint16(mdhd[20:22], 0x55c4)
+
+	hdlr := make([]byte, 24)
+	copy(hdlr[8:12], "vide")
+	hdlr = append(hdlr, "VideoHandler\x00"...)
+
+	// Graphics mode is copy, the flags must be 1.
+	vmhd := []byte{0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0}
+
+	// Single data reference with the flag meaning the media is in the same file.
+	dref := append([]byte{0, 0, 0, 0, 0, 0, 0, 1}, box("url ", []byte{0, 0, 0, 1})...)
+
+	// Sample description, time-to-sample, sample-to-chunk, sample size and chunk offset tables are empty.
+	empty := make([]byte, 8)
+	stbl := bytes.Join([][]byte{
+		box("stsd", empty),
+		box("stts", empty),
+		box("stsc", empty),
+		box("stsz", make([]byte, 12)),
+		box("stco", empty),
+	}, nil)
+
+	minf := append(box("vmhd", vmhd), box("dinf", box("dref", dref))...)
+	minf = append(minf, box("stbl", stbl)...)
+	mdia := bytes.Join([][]byte{box("mdhd", mdhd), box("hdlr", hdlr), box("minf", minf)}, nil)
+	trak := append(box("tkhd", tkhd), box("mdia", mdia)...)
+
+	return append(box("ftyp", ftyp.Bytes()), box("moov", append(box("mvhd", mvhd), box("trak", trak)...))...)
+}
+
+func placeholderPDF() []byte {
+	content := "BT /F1 24 Tf 72 720 Td (Placeholder document) Tj ET"
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /MediaBox [0 0 612 792] /Contents 4 0 R " +
+			"/Resources << /Font << /F1 5 0 R >> >> >>",
+		fmt.Sprintf("<< /Length %d >>\nstream\n%s\nendstream", len(content), content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = buf.Len()
+		fmt.Fprintf(&buf, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+
+	xref := buf.Len()
+	fmt.Fprintf(&buf, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&buf, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&buf, "trailer\n<< /Size %d /Root 1 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xref)
+	return buf.Bytes()
+}
+
+func riff(format string, data []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("RIFF")
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(data)+4))
+	buf.WriteString(format)
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+func chunk(name string, data []byte) []byte {
+	var buf bytes.Buffer
+	buf.WriteString(name)
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(len(data)))
+	buf.Write(data)
+	if len(data)%2 == 1 {
+		buf.WriteByte(0)
+	}
+	return buf.Bytes()
+}
+
+func box(name string, data []byte) []byte {
+	var buf bytes.Buffer
+	_ = binary.Write(&buf, binary.BigEndian, uint32(len(data)+8))
+	buf.WriteString(name)
+	buf.Write(data)
+	return buf.Bytes()
+}
+
+// oggPage encodes the packets into a single OGG page. Every packet must be shorter than 255 bytes
+// except the last one.
+func oggPage(serial, sequence uint32, flags byte, granule uint64, packets [][]byte) []byte {
+	var segments []byte
+	var body []byte
+	for _, packet := range packets {
+		size := len(packet)
+		for ; size >= 255; size -= 255 {
+			segments = append(segments, 255)
+		}
+		segments = append(segments, byte(size))
+		body = append(body, packet...)
+	}
+
+	var buf bytes.Buffer
+	buf.WriteString("OggS")
+	buf.Write([]byte{0, flags})
+	_ = binary.Write(&buf, binary.LittleEndian, granule)
+	_ = binary.Write(&buf, binary.LittleEndian, serial)
+	_ = binary.Write(&buf, binary.LittleEndian, sequence)
+	_ = binary.Write(&buf, binary.LittleEndian, uint32(0))
+	buf.WriteByte(byte(len(segments)))
+	buf.Write(segments)
+	buf.Write(body)
+
+	page := buf.Bytes()
+	binary.LittleEndian.PutUint32(page[22:26], oggCRC(page))
+	return page
+}
+
+func oggCRC(data []byte) uint32 {
+	var crc uint32
+	for _, b := range data {
+		crc ^= uint32(b) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	return crc
+}
+
+// bitWriter writes values least significant bit first as required by the VP8L bitstream.
+type bitWriter struct {
+	buf   []byte
+	acc   uint64
+	count uint
+}
+
+func (w *bitWriter) write(value uint32, bits uint) {
+	w.acc |= uint64(value) << w.count
+	w.count += bits
+	for w.count >= 8 {
+		w.buf = append(w.buf, byte(w.acc))
+		w.acc >>= 8
+		w.count -= 8
+	}
+}
+
+func (w *bitWriter) bytes() []byte {
+	if w.count > 0 {
+		return append(w.buf, byte(w.acc))
+	}
+	return w.buf
+}
@@ -0,0 +1,60 @@
+package coreapp
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// boxes collects the boxes of the MP4 container by their paths, e.g. moov/trak/tkhd.
+func boxes(t *testing.T, data []byte, prefix string, found map[string][]byte) {
+	t.Helper()
+	containers := map[string]bool{"moov": true, "trak": true, "mdia": true, "minf": true, "dinf": true, "stbl": true}
+	for len(data) > 0 {
+		if len(data) < 8 {
+			t.Fatalf("truncated box in %s", prefix)
+		}
+		size := int(binary.BigEndian.Uint32(data[0:4]))
+		if size < 8 || size > len(data) {
+			t.Fatalf("invalid size %d of %s%s", size, prefix, data[4:8])
+		}
+
+		path := prefix + string(data[4:8])
+		found[path] = data[8:size]
+		if containers[string(data[4:8])] {
+			boxes(t, data[8:size], path+"/", found)
+		}
+		data = data[size:]
+	}
+}
+
+func TestPlaceholderMP4(t *testing.T) {
+	found := map[string][]byte{}
+	boxes(t, placeholderMP4(), "", found)
+
+	for _, path := range []string{"ftyp", "moov/mvhd", "moov/trak/tkhd", "moov/trak/mdia/mdhd", "moov/trak/mdia/hdlr",
+		"moov/trak/mdia/minf/vmhd", "moov/trak/mdia/minf/dinf/dref", "moov/trak/mdia/minf/stbl/stsd",
+		"moov/trak/mdia/minf/stbl/stts", "moov/trak/mdia/minf/stbl/stsc", "moov/trak/mdia/minf/stbl/stsz",
+		"moov/trak/mdia/minf/stbl/stco"} {
+		if _, ok := found[path]; !ok {
+			t.Fatalf("%s is missing", path)
+		}
+	}
+	if handler := string(found["moov/trak/mdia/hdlr"][8:12]); handler != "vide" {
+		t.Fatalf("unexpected handler %s", handler)
+	}
+	if id := binary.BigEndian.Uint32(found["moov/trak/tkhd"][12:16]); id != 1 {
+		t.Fatalf("unexpected track ID %d", id)
+	}
+}
+
+func TestPlaceholdersAreValid(t *testing.T) {
+	for _, msgType := range []MessageType{"image", "audio", "sticker", "video", "document"} {
+		data, mimeType, _ := placeholderMedia(msgType)
+		if sniffed := SniffMIMEType(data, ""); sniffed != mimeType {
+			t.Fatalf("%s placeholder is detected as %s instead of %s", msgType, sniffed, mimeType)
+		}
+		if err := validateMedia(msgType, mimeType, data); err != nil {
+			t.Fatalf("%s placeholder is invalid: %s", msgType, err.Details)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package coreapp
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/gommon/log"
+)
+
+// newUpstreamProxy returns reverse proxy which forwards API requests to the real Coreapp.
+func newUpstreamProxy(target *url.URL) *httputil.ReverseProxy {
+	proxy := httputil.NewSingleHostReverseProxy(target)
+	director := proxy.Director
+	proxy.Director = func(req *http.Request) {
+		director(req)
+		req.Host = target.Host
+	}
+	proxy.ErrorHandler = func(w http.ResponseWriter, req *http.Request, err error) {
+		log.Printf("error: %s\n", err)
+		w.Header().Set("Content-Type", "application/json; charset=utf-8")
+		w.WriteHeader(http.StatusBadGateway)
+		_ = json.NewEncoder(w).Encode(BaseResponse{Errors: []Error{NewError(ErrorCodeServiceNotReady, err.Error())}})
+	}
+	return proxy
+}
+
+// proxy forwards API requests to the upstream if proxy mode is enabled.
+func (s *Server) proxy(c *gin.Context) {
+	if s.upstream == nil {
+		c.Next()
+		return
+	}
+
+	s.upstream.ServeHTTP(c.Writer, c.Request)
+	c.Abort()
+}
+
+// upstreamOnly passes the API requests which don't match any mock route to the upstream if proxy mode is enabled.
+// Other requests get the default 404 response.
+func (s *Server) upstreamOnly(c *gin.Context) {
+	if s.upstream == nil || !strings.HasPrefix(c.Request.URL.Path, "/v1/") {
+		c.Abort()
+		return
+	}
+	c.Next()
+}
+
+// upstreamWebhookHandler receives webhooks from the upstream Coreapp and delivers them to the configured webhook.
+// Webhooks are recorded even if there is no primary webhook to deliver them to.
+func (s *Server) upstreamWebhookHandler(c *gin.Context) {
+	payload, err := io.ReadAll(c.Request.Body)
+	if err != nil || !json.Valid(payload) {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "webhook payload must be a valid JSON"})
+		return
+	}
+
+	var webhook InboundWebhook
+	_ = json.Unmarshal(payload, &webhook)
+	recipients := webhook.recipients()
+
+	mock := s.config()
+	if mock.Webhook == "" && s.recorder != nil {
+		s.recorder.Record(Record{
+			Time: Now(),
+			Kind: RecordWebhook,
+			Webhook: &Delivery{
+				Time:       Now(),
+				Payload:    payload,
+				Recipients: recipients,
+			},
+		})
+	}
+	if !mock.hasWebhooks() {
+		c.Status(http.StatusOK)
+		return
+	}
+
+	code, err := s.shooter.SendPayload(payload, recipients...)
+	if err != nil {
+		log.Printf("error: %s\n", err)
+	} else {
+		log.Printf("upstream webhook code: %d\n", code)
+	}
+	c.Status(http.StatusOK)
+}
+
+// Fixtures serves recorded API responses. Requests are matched by method, path and body.
+type Fixtures struct {
+	mu      sync.Mutex
+	records []Record
+	served  map[int]bool
+}
+
+func NewFixtures(records []Record) *Fixtures {
+	fixtures := &Fixtures{served: map[int]bool{}}
+	for _, record := range records {
+		if record.Kind == RecordAPI && record.Request != nil && record.Response != nil {
+			fixtures.records = append(fixtures.records, record)
+		}
+	}
+	return fixtures
+}
+
+// LoadFixtures reads fixtures from the JSONL recording.
+func LoadFixtures(path string) (*Fixtures, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	records, err := ReadRecords(file)
+	if err != nil {
+		return nil, err
+	}
+	return NewFixtures(records), nil
+}
+
+// Match returns the recorded response to the request. Identical requests get the recorded responses in order,
+// the last one is repeated after that.
+func (f *Fixtures) Match(method, path string, body []byte) (HTTPEvent, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var request HTTPEvent
+	request.setBody(body)
+	last := -1
+	for i, record := range f.records {
+		if record.Request.Method != method || record.Request.Path != path || !record.Request.sameBody(request) {
+			continue
+		}
+		if !f.served[i] {
+			f.served[i] = true
+			return *record.Response, true
+		}
+		last = i
+	}
+	if last == -1 {
+		return HTTPEvent{}, false
+	}
+	return *f.records[last].Response, true
+}
+
+// canonicalJSON re-encodes JSON to make the comparison independent of the formatting and the keys order.
+func canonicalJSON(body json.RawMessage) []byte {
+	if len(body) == 0 {
+		return nil
+	}
+
+	var value interface{}
+	if err := json.Unmarshal(body, &value); err != nil {
+		return body
+	}
+	canonical, _ := json.Marshal(value)
+	return canonical
+}
+
+// serveFixtures serves the recorded response if there is one for the request. Other requests are handled by the mock.
+func (s *Server) serveFixtures(c *gin.Context) {
+	if s.fixtures == nil {
+		c.Next()
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+	c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+	response, ok := s.fixtures.Match(c.Request.Method, c.Request.URL.RequestURI(), body)
+	if !ok {
+		c.Next()
+		return
+	}
+
+	if response.ContentType != "" {
+		c.Header("Content-Type", response.ContentType)
+	}
+	c.Status(response.Status)
+	_, _ = c.Writer.Write(response.rawBody())
+	c.Abort()
+}
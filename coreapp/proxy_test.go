@@ -0,0 +1,40 @@
+package coreapp
+
+import (
+	"bytes"
+	"net/http"
+	"path/filepath"
+	"testing"
+)
+
+func TestFixturesServeRecordedBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "traffic.jsonl")
+	recorder, err := NewRecorder(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Media is not a valid UTF-8 and is larger than the events preview.
+	file := newMediaFile(bytes.Repeat([]byte{0xff, 0x00}, maxEventBody), "application/pdf")
+	s := NewServer(Options{Recorder: recorder})
+	s.media[file.ID] = file
+	if w := serve(s, http.MethodGet, "/v1/media/"+file.ID, ""); w.Code != http.StatusOK {
+		t.Fatalf("media is not downloaded: %d", w.Code)
+	}
+	if err := recorder.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures, err := LoadFixtures(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	replayed := NewServer(Options{Fixtures: fixtures})
+	w := serve(replayed, http.MethodGet, "/v1/media/"+file.ID, "")
+	if w.Code != http.StatusOK || !bytes.Equal(w.Body.Bytes(), file.Data) {
+		t.Fatalf("recorded body is not served: %d, %d bytes", w.Code, w.Body.Len())
+	}
+	if contentType := w.Header().Get("Content-Type"); contentType != "application/pdf" {
+		t.Fatalf("unexpected content type: %s", contentType)
+	}
+}
@@ -0,0 +1,97 @@
+package coreapp
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math/rand"
+	"sync"
+	"time"
+	"unsafe"
+)
+
+const letterBytes = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+const (
+	letterIdxBits = 6                    // 6 bits to represent a letter index
+	letterIdxMask = 1<<letterIdxBits - 1 // All 1-bits, as many as letterIdxBits
+	letterIdxMax  = 63 / letterIdxBits   // # of letter indices fitting in 63 bits
+)
+
+var src = newLockedSource(time.Now().UnixNano())
+
+// lockedSource is safe for concurrent use unlike the sources returned by rand.NewSource.
+type lockedSource struct {
+	mu   sync.Mutex
+	src  rand.Source
+	seed int64
+}
+
+func newLockedSource(seed int64) *lockedSource {
+	return &lockedSource{src: rand.NewSource(seed), seed: seed}
+}
+
+func (s *lockedSource) Int63() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.src.Int63()
+}
+
+func (s *lockedSource) Seed(seed int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.src.Seed(seed)
+	s.seed = seed
+}
+
+// derive returns the source which depends only on the seed and the key.
+func (s *lockedSource) derive(key string) rand.Source {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(key))
+	return rand.NewSource(s.seed ^ int64(h.Sum64()))
+}
+
+// SetSeed makes generated IDs reproducible. The source is shared by all servers of the process, so IDs of
+// the tenants depend on the order of the requests to all of them. Values generated by the webhook goroutines,
+// like the conversation IDs, are derived from the message IDs and don't depend on the order of the goroutines.
+func SetSeed(seed int64) {
+	src.Seed(seed)
+}
+
+func RandomString(n int) string {
+	return randomString(src, n)
+}
+
+func randomString(src rand.Source, n int) string {
+	b := make([]byte, n)
+	// A src.Int63() generates 63 random bits, enough for letterIdxMax characters!
+	for i, cache, remain := n-1, src.Int63(), letterIdxMax; i >= 0; {
+		if remain == 0 {
+			cache, remain = src.Int63(), letterIdxMax
+		}
+		if idx := int(cache & letterIdxMask); idx < len(letterBytes) {
+			b[i] = letterBytes[idx]
+			i--
+		}
+		cache >>= letterIdxBits
+		remain--
+	}
+
+	return *(*string)(unsafe.Pointer(&b))
+}
+
+// RandomUUID returns random UUID version 4.
+func RandomUUID() string {
+	b := make([]byte, 16)
+	for i := 0; i < len(b); i += 7 {
+		v := src.Int63()
+		for j := i; j < i+7 && j < len(b); j++ {
+			b[j] = byte(v)
+			v >>= 8
+		}
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])
+}
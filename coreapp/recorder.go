@@ -0,0 +1,163 @@
+package coreapp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/gommon/log"
+)
+
+type RecordKind string
+
+const (
+	RecordAPI     RecordKind = "api"
+	RecordWebhook RecordKind = "webhook"
+)
+
+// maxRecordLine is the maximum length of the line in the recording.
+const maxRecordLine = 16 * 1024 * 1024
+
+// Record is a line of the recording: API request with the response or webhook delivery attempt.
+type Record struct {
+	Time     time.Time  `json:"time"`
+	Kind     RecordKind `json:"kind"`
+	Request  *HTTPEvent `json:"request,omitempty"`
+	Response *HTTPEvent `json:"response,omitempty"`
+	Webhook  *Delivery  `json:"webhook,omitempty"`
+}
+
+// Recorder appends records to the JSONL file.
+type Recorder struct {
+	mu   sync.Mutex
+	file *os.File
+	enc  *json.Encoder
+}
+
+func NewRecorder(path string) (*Recorder, error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &Recorder{file: file, enc: json.NewEncoder(file)}, nil
+}
+
+func (r *Recorder) Record(record Record) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.enc.Encode(record); err != nil {
+		log.Printf("error: %s\n", err)
+	}
+}
+
+func (r *Recorder) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.file.Close()
+}
+
+// ReadRecords parses the JSONL recording.
+func ReadRecords(r io.Reader) ([]Record, error) {
+	var records []Record
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, maxRecordLine)
+	for line := 1; scanner.Scan(); line++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+
+		var record Record
+		if err := json.Unmarshal(scanner.Bytes(), &record); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		records = append(records, record)
+	}
+	return records, scanner.Err()
+}
+
+// webhookRecords returns successfully encoded deliveries to the primary webhook from the recording.
+func webhookRecords(records []Record) []Record {
+	var webhooks []Record
+	for _, record := range records {
+		if record.Kind == RecordWebhook && record.Webhook != nil && len(record.Webhook.Payload) > 0 &&
+			record.Webhook.Subscriber == "" && record.Webhook.Chaos != ChaosDrop {
+			webhooks = append(webhooks, record)
+		}
+	}
+	return webhooks
+}
+
+// Replay delivers recorded webhooks to the configured webhook keeping the original intervals between them.
+// Intervals are waited on the mock clock, so with the virtual clock the next webhook is sent once the clock
+// is moved past it. API calls from the recording are not repeated: they are made by the application under test.
+func (s *Server) Replay(records []Record) error {
+	if !s.config().hasWebhooks() {
+		return ErrNoWebhook
+	}
+
+	webhooks := webhookRecords(records)
+	for i, record := range webhooks {
+		if i > 0 {
+			Sleep(record.Time.Sub(webhooks[i-1].Time))
+		}
+
+		code, err := s.shooter.SendPayload(record.Webhook.Payload, record.Webhook.Recipients...)
+		if err != nil {
+			log.Printf("error: %s\n", err)
+			continue
+		}
+		log.Printf("replayed webhook code: %d\n", code)
+	}
+	return nil
+}
+
+// ReplayFile replays webhooks from the JSONL recording.
+func (s *Server) ReplayFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	records, err := ReadRecords(file)
+	if err != nil {
+		return err
+	}
+	return s.Replay(records)
+}
+
+// replayHandler starts replay of the JSONL recording from the request body in the background.
+func (s *Server) replayHandler(c *gin.Context) {
+	records, err := ReadRecords(c.Request.Body)
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if !s.config().hasWebhooks() {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": ErrNoWebhook.Error()})
+		return
+	}
+
+	webhooks := webhookRecords(records)
+	var duration time.Duration
+	if len(webhooks) > 0 {
+		duration = webhooks[len(webhooks)-1].Time.Sub(webhooks[0].Time)
+	}
+
+	go func() {
+		if err := s.Replay(webhooks); err != nil && !errors.Is(err, ErrNoWebhook) {
+			log.Printf("error: %s\n", err)
+		}
+	}()
+
+	c.JSON(http.StatusAccepted, gin.H{"webhooks": len(webhooks), "duration": duration.String()})
+}
@@ -0,0 +1,135 @@
+package coreapp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	// ScenarioHeader selects the scenario for a single API request.
+	ScenarioHeader = "X-Mock-Scenario"
+	// DefaultScenario is the initial configuration without any scenario applied.
+	DefaultScenario = "default"
+
+	contextMockKey = "mock"
+)
+
+var ErrUnknownScenario = errors.New("unknown scenario")
+
+// scenarioMock returns the configuration without any scenario with the scenario settings applied on top of it.
+// Account state is not a part of the scenario and is kept as is. Caller must hold the lock.
+func (s *Server) scenarioMock(name string) (Mock, error) {
+	// Scenario settings are decoded into the copy, so the maps of the initial configuration are not changed.
+	mock, err := copyMock(s.initial)
+	if err != nil {
+		return mock, err
+	}
+	mock.Scenarios = s.mock.Scenarios
+	mock.AccountState = s.mock.AccountState
+	if name == DefaultScenario {
+		mock.Scenario = ""
+		return mock, nil
+	}
+
+	scenario, ok := s.mock.Scenarios[name]
+	if !ok {
+		return mock, fmt.Errorf("%w %s", ErrUnknownScenario, name)
+	}
+
+	if err := json.Unmarshal(scenario, &mock); err != nil {
+		return mock, fmt.Errorf("invalid scenario %s: %w", name, err)
+	}
+	mock.Scenarios = s.mock.Scenarios
+	mock.Scenario = name
+
+	if err := validate.Struct(mock); err != nil {
+		return mock, fmt.Errorf("invalid scenario %s: %w", name, err)
+	}
+
+	return mock, nil
+}
+
+// configFor returns the mock configuration for the request, which can be overridden by the scenario header.
+func (s *Server) configFor(c *gin.Context) Mock {
+	if mock, ok := c.Get(contextMockKey); ok {
+		return mock.(Mock)
+	}
+	return s.config()
+}
+
+// contextMock is configFor for the callers which hold the lock.
+func (s *Server) contextMock(c *gin.Context) Mock {
+	if mock, ok := c.Get(contextMockKey); ok {
+		return mock.(Mock)
+	}
+	return s.mock
+}
+
+// scenario applies the scenario from the request header and the response delay.
+func (s *Server) scenario(c *gin.Context) {
+	mock := s.config()
+	if name := c.GetHeader(ScenarioHeader); name != "" {
+		var err error
+		s.mu.RLock()
+		mock, err = s.scenarioMock(name)
+		s.mu.RUnlock()
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	c.Set(contextMockKey, mock)
+	// Response delay is real even with the virtual clock, since nothing advances that clock during the request.
+	if mock.ResponseDelay > 0 {
+		time.Sleep(time.Duration(mock.ResponseDelay) * time.Millisecond)
+	}
+	c.Next()
+}
+
+func (s *Server) scenariosHandler(c *gin.Context) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	names := []string{DefaultScenario}
+	for name := range s.mock.Scenarios {
+		names = append(names, name)
+	}
+	sort.Strings(names[1:])
+
+	active := s.mock.Scenario
+	if active == "" {
+		active = DefaultScenario
+	}
+
+	c.JSON(http.StatusOK, gin.H{"active": active, "scenarios": names})
+}
+
+func (s *Server) activateScenarioHandler(c *gin.Context) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	mock, err := s.scenarioMock(c.Param("name"))
+	if errors.Is(err, ErrUnknownScenario) {
+		c.AbortWithStatusJSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if mock.Webhook != s.mock.Webhook || mock.VerifyToken != s.mock.VerifyToken {
+		s.verified = false
+	}
+
+	s.mock = mock
+	s.updateShooter()
+	c.JSON(http.StatusOK, s.mock)
+}
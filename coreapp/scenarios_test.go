@@ -0,0 +1,31 @@
+package coreapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+)
+
+func TestActivateScenario(t *testing.T) {
+	mock := DefaultMock()
+	mock.Scenarios = map[string]json.RawMessage{
+		"read":    json.RawMessage(`{"messages_success_status":"read"}`),
+		"invalid": json.RawMessage(`{"messages_success_status":"unknown"}`),
+	}
+	s := NewServer(Options{Mock: &mock})
+
+	tests := []struct {
+		name string
+		code int
+	}{
+		{"missing", http.StatusNotFound},
+		{"invalid", http.StatusBadRequest},
+		{"read", http.StatusOK},
+		{DefaultScenario, http.StatusOK},
+	}
+	for _, test := range tests {
+		if w := serve(s, http.MethodPost, "/mock/scenarios/"+test.name, ""); w.Code != test.code {
+			t.Fatalf("unexpected code for %s: %d %s", test.name, w.Code, w.Body.String())
+		}
+	}
+}
@@ -0,0 +1,654 @@
+package coreapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-playground/validator/v10"
+	"github.com/labstack/gommon/log"
+)
+
+var (
+	validate       = validator.New()
+	NotDigitsRegex = regexp.MustCompile("\\D+")
+)
+
+type Mock struct {
+	ContactsSuccess  bool              `json:"contacts_success"`
+	MessagesSuccess  bool              `json:"messages_success"`
+	MessagesStatus   string            `json:"messages_success_status" validate:"oneof=sent read failed"`
+	Webhook          string            `json:"webhook" validate:"omitempty,url,startswith=http"`
+	WebhookHeaders   map[string]string `json:"webhook_headers"`
+	AccountState     AccountState      `json:"account_state" validate:"oneof=unregistered code_sent registered"`
+	VerificationCode string            `json:"verification_code" validate:"required"`
+	// BlockChangedNumbers rejects messages to the customers which changed their number until they're rechecked.
+	BlockChangedNumbers bool `json:"block_changed_numbers"`
+	// IdentityCheck rejects messages to the customers with unacknowledged identity change.
+	IdentityCheck bool `json:"identity_check"`
+	// RecipientErrors maps WhatsApp ID of the recipient to the error code which is sent in the failed status.
+	RecipientErrors map[string]int `json:"recipient_errors"`
+	// PhoneNumberID and BusinessAccountID identify the number in the Cloud API mode.
+	PhoneNumberID     string `json:"phone_number_id" validate:"required,numeric"`
+	BusinessAccountID string `json:"business_account_id" validate:"required,numeric"`
+	// AppSecret enables X-Hub-Signature-256 payload signatures.
+	AppSecret string `json:"app_secret"`
+	// VerifyToken requires webhook verification handshake before delivery in the Cloud API mode.
+	VerifyToken string `json:"verify_token"`
+	// ContactsStatus is returned for every contact by /v1/contacts.
+	ContactsStatus ContactStatus `json:"contacts_status" validate:"oneof=valid processing invalid failed"`
+	// ResponseDelay delays every API response by the provided amount of milliseconds.
+	ResponseDelay int `json:"response_delay_ms" validate:"min=0"`
+	// WebhookSubscribers are additional webhook targets with their own headers, filters and retries.
+	WebhookSubscribers []WebhookSubscriber `json:"webhook_subscribers,omitempty" validate:"unique=Name,dive"`
+	// WebhookBatch makes webhooks accumulate and be sent as a single payload.
+	WebhookBatch *WebhookBatch `json:"webhook_batch,omitempty"`
+	// Chaos makes the webhook deliveries misbehave with the provided probabilities.
+	Chaos *Chaos `json:"chaos,omitempty"`
+	// Scenario is the name of the active scenario.
+	Scenario string `json:"scenario,omitempty"`
+	// Scenarios are named sets of settings which are applied on top of the initial configuration.
+	Scenarios map[string]json.RawMessage `json:"scenarios,omitempty"`
+}
+
+// hasWebhooks returns true if the primary webhook or any subscriber is configured.
+func (m Mock) hasWebhooks() bool {
+	return m.Webhook != "" || len(m.WebhookSubscribers) > 0
+}
+
+func DefaultMock() Mock {
+	return Mock{
+		ContactsSuccess:   true,
+		MessagesSuccess:   true,
+		MessagesStatus:    "sent",
+		Webhook:           "",
+		WebhookHeaders:    map[string]string{},
+		AccountState:      AccountStateRegistered,
+		VerificationCode:  "123456",
+		PhoneNumberID:     "100000000000001",
+		BusinessAccountID: "100000000000002",
+		ContactsStatus:    ContactStatusValid,
+	}
+}
+
+type Options struct {
+	// Cloud enables Cloud API compatibility mode.
+	Cloud bool
+	// Mock is the initial mock configuration. Defaults are used if it's nil.
+	Mock *Mock
+	// Account is the initial WhatsApp account. Defaults are used if it's nil.
+	Account *Account
+	// Store persists the state after every change. If it's nil, only the snapshots are kept in memory.
+	Store Store
+	// Recorder writes API calls and webhook deliveries to the JSONL file if it's not nil.
+	Recorder *Recorder
+	// Upstream is URL of the real Coreapp. API requests are forwarded to it if it's not nil.
+	Upstream *url.URL
+	// Fixtures are served instead of the mock responses for the matching API requests.
+	Fixtures *Fixtures
+}
+
+type Server struct {
+	g        *gin.Engine
+	cloud    bool
+	verified bool
+	shooter  *Shooter
+	events   *EventBus
+	recorder *Recorder
+	upstream *httputil.ReverseProxy
+	fixtures *Fixtures
+	store    Store
+	mu       sync.RWMutex
+	mock     Mock
+	// initial is the configuration without any scenario applied, including the changes made at runtime.
+	initial  Mock
+	account  Account
+	settings ApplicationSettings
+	profile  BusinessProfile
+	groups   map[string]*GroupInfo
+	contacts map[string]*ContactState
+	journal  []JournalEntry
+	media    map[string]MediaFile
+
+	expectations  []Expectation
+	conversations map[string]InboundStatusConversation
+
+	// autosave is true if the state is saved after every change.
+	autosave bool
+	// saveMu serializes saves of the state and guards savedMedia, savePending is guarded by pendingMu.
+	saveMu      sync.Mutex
+	savedMedia  map[string]bool
+	pendingMu   sync.Mutex
+	savePending bool
+}
+
+func NewServer(opts Options) (s *Server) {
+	s = &Server{
+		g:     gin.New(),
+		cloud: opts.Cloud,
+		mock:  DefaultMock(),
+		account: Account{
+			CC:          "1",
+			PhoneNumber: "5550000000",
+		},
+		settings: DefaultApplicationSettings(),
+		groups:   map[string]*GroupInfo{},
+		contacts: map[string]*ContactState{},
+		media:    map[string]MediaFile{},
+		events:   NewEventBus(),
+
+		conversations: map[string]InboundStatusConversation{},
+		savedMedia:    map[string]bool{},
+	}
+	if opts.Mock != nil {
+		s.mock = *opts.Mock
+	}
+	if opts.Account != nil {
+		s.account = *opts.Account
+	}
+	s.initial = s.mock
+	s.store = opts.Store
+	s.autosave = s.store != nil
+	if s.store == nil {
+		s.store = NewMemoryStore()
+	}
+	s.recorder = opts.Recorder
+	s.fixtures = opts.Fixtures
+	if opts.Upstream != nil {
+		s.upstream = newUpstreamProxy(opts.Upstream)
+	}
+	s.updateShooter()
+	s.g.GET("/mock", s.mockData)
+	s.g.POST("/mock", s.updateMockData)
+	s.g.POST("/mock/contacts/:wa_id/number", s.changeNumberHandler)
+	s.g.POST("/mock/contacts/:wa_id/identity", s.changeIdentityHandler)
+	s.g.POST("/mock/inbound", s.injectInboundHandler)
+	s.g.POST("/mock/errors", s.injectErrorsHandler)
+	s.g.POST("/mock/webhook/verify", s.verifyWebhookHandler)
+	s.g.POST("/mock/snapshot", s.snapshotHandler)
+	s.g.POST("/mock/restore", s.restoreHandler)
+	s.g.GET("/mock/messages", s.journalHandler)
+	s.g.DELETE("/mock/messages", s.clearJournalHandler)
+	s.g.POST("/mock/messages/:id/status", s.messageStatusHandler)
+	s.g.GET("/mock/webhooks", s.webhooksHandler)
+	s.g.GET("/mock/webhooks/subscribers", s.subscribersHandler)
+	s.g.POST("/mock/expectations", s.addExpectationHandler)
+	s.g.DELETE("/mock/expectations", s.clearExpectationsHandler)
+	s.g.GET("/mock/expectations/verify", s.verifyExpectationsHandler)
+	s.g.GET("/mock/scenarios", s.scenariosHandler)
+	s.g.POST("/mock/scenarios/:name", s.activateScenarioHandler)
+	s.g.GET("/mock/events", s.eventsHandler)
+	s.g.GET("/mock/clock", s.clockHandler)
+	s.g.POST("/mock/clock", s.advanceClockHandler)
+	s.g.POST("/mock/replay", s.replayHandler)
+	s.g.POST("/mock/upstream/webhook", s.upstreamWebhookHandler)
+	s.g.GET("/console", s.consoleHandler)
+	api := s.g.Group("/v1", s.capture, s.proxy, s.serveFixtures, s.scenario)
+	{
+		api.POST("/contacts", s.contactsHandler)
+		api.GET("/contacts/:wa_id/identity", s.identityHandler)
+		api.PUT("/contacts/:wa_id/identity", s.acknowledgeIdentityHandler)
+		api.POST("/messages", s.registeredOnly, s.messagesHandler)
+		api.POST("/account", s.accountHandler)
+		api.POST("/account/verify", s.accountVerifyHandler)
+		api.POST("/account/two-step", s.setTwoStepHandler)
+		api.DELETE("/account/two-step", s.removeTwoStepHandler)
+		api.GET("/settings/application", s.applicationSettingsHandler)
+		api.PATCH("/settings/application", s.updateApplicationSettingsHandler)
+		api.GET("/settings/business/profile", s.businessProfileHandler)
+		api.POST("/settings/business/profile", s.updateBusinessProfileHandler)
+		api.POST("/settings/backup", s.backupHandler)
+		api.POST("/settings/restore", s.restoreSettingsHandler)
+		api.GET("/settings/application/media/providers", s.mediaProvidersHandler)
+		api.POST("/settings/application/media/providers", s.updateMediaProvidersHandler)
+		api.DELETE("/settings/application/media/providers/:name", s.deleteMediaProviderHandler)
+		api.POST("/media", s.uploadMediaHandler)
+		api.GET("/media/:id", s.downloadMediaHandler)
+		api.DELETE("/media/:id", s.deleteMediaHandler)
+
+		groups := api.Group("/groups", s.registeredOnly)
+		groups.POST("", s.createGroupHandler)
+		groups.GET("", s.groupsHandler)
+		groups.GET("/:id", s.groupHandler)
+		groups.PUT("/:id", s.updateGroupHandler)
+		groups.GET("/:id/invite", s.groupInviteHandler)
+		groups.DELETE("/:id/invite", s.revokeGroupInviteHandler)
+		groups.PUT("/:id/participants", s.addGroupParticipantsHandler)
+		groups.DELETE("/:id/participants", s.removeGroupParticipantsHandler)
+		groups.PATCH("/:id/admins", s.addGroupAdminsHandler)
+		groups.DELETE("/:id/admins", s.removeGroupAdminsHandler)
+		groups.POST("/:id/leave", s.leaveGroupHandler)
+	}
+	s.g.NoRoute(s.upstreamOnly, s.capture, s.proxy)
+	if opts.Cloud {
+		s.g.POST("/:version/:phone_number_id/messages", s.capture, s.scenario, s.cloudMessagesHandler)
+	}
+	return s
+}
+
+func (s *Server) Run(addr ...string) error {
+	return s.g.Run(addr...)
+}
+
+// Handler returns HTTP handler of the mock, e.g. for httptest.Server.
+func (s *Server) Handler() http.Handler {
+	return s.g
+}
+
+// Configure changes the mock configuration. The change is discarded if the resulting configuration is invalid.
+func (s *Server) Configure(fn func(mock *Mock)) error {
+	s.mu.Lock()
+	defer s.unlock()
+
+	// The change is applied to the configuration without the scenario too, so it survives scenario switches.
+	current := s.mock
+	fn(&current)
+	if err := validate.Struct(current); err != nil {
+		return err
+	}
+	initial := s.initial
+	fn(&initial)
+	if err := validate.Struct(initial); err != nil {
+		return err
+	}
+
+	if current.Webhook != s.mock.Webhook || current.VerifyToken != s.mock.VerifyToken {
+		s.verified = false
+	}
+
+	s.mock = current
+	s.initial = initial
+	s.updateShooter()
+	return nil
+}
+
+func (s *Server) updateShooter() {
+	if s.shooter == nil {
+		s.shooter = NewShooter(s.mock.Webhook, s.mock.WebhookHeaders)
+		s.shooter.OnDelivery = func(delivery Delivery) {
+			s.events.Publish(Event{
+				Kind:       EventWebhook,
+				Time:       delivery.Time,
+				Recipients: delivery.Recipients,
+				Data:       delivery,
+			})
+			if s.recorder != nil {
+				s.recorder.Record(Record{
+					Time:    delivery.Time,
+					Kind:    RecordWebhook,
+					Webhook: &delivery,
+				})
+			}
+		}
+	}
+	s.shooter.Configure(s.shooterConfig(s.mock))
+}
+
+// shooterConfig returns the webhook delivery configuration for the mock configuration. Verification of the current
+// webhook doesn't apply to another webhook or verify token. Caller must hold the lock.
+func (s *Server) shooterConfig(mock Mock) ShooterConfig {
+	verified := s.verified && mock.Webhook == s.mock.Webhook && mock.VerifyToken == s.mock.VerifyToken
+	return ShooterConfig{
+		Webhook:           mock.Webhook,
+		Headers:           mock.WebhookHeaders,
+		Cloud:             s.cloud,
+		BusinessAccountID: mock.BusinessAccountID,
+		Metadata: CloudMetadata{
+			DisplayPhoneNumber: s.account.WaID(),
+			PhoneNumberID:      mock.PhoneNumberID,
+		},
+		AppSecret:   mock.AppSecret,
+		Verified:    verified || !(s.cloud && mock.VerifyToken != ""),
+		Chaos:       mock.Chaos,
+		Batch:       mock.WebhookBatch,
+		Subscribers: mock.WebhookSubscribers,
+	}
+}
+
+// shooterFor returns the shooter which delivers webhooks with the request configuration, e.g. the one selected
+// by the scenario header. Deliveries are logged together with the ones of the main shooter.
+func (s *Server) shooterFor(mock Mock) *Shooter {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.shooter.With(s.shooterConfig(mock))
+}
+
+// config returns a copy of the current mock configuration.
+func (s *Server) config() Mock {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.mock
+}
+
+func (s *Server) groupExists(id string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	_, ok := s.groups[id]
+	return ok
+}
+
+// sendSystem delivers system message to the webhook of the mock configuration. Caller must hold the lock.
+func (s *Server) sendSystem(mock Mock, from, groupID string, system MessageSystem) {
+	if !mock.hasWebhooks() {
+		return
+	}
+
+	shooter := s.shooter.With(s.shooterConfig(mock))
+	id := MessageID(inboundIDFormat(s.cloud), DirectionInbound, from)
+	go func() {
+		Sleep(time.Millisecond * 500)
+
+		code, err := shooter.Send(InboundWebhook{
+			Messages: []InboundMessage{systemMessage(id, system, from, groupID)},
+		})
+		if err != nil {
+			log.Printf("error: %s\n", err)
+			return
+		}
+		log.Printf("system webhook code: %d\n", code)
+	}()
+}
+
+func (s *Server) baseResponseOk() BaseResponse {
+	return BaseResponse{
+		Meta: &Metadata{
+			Success:   true,
+			APIStatus: "stable",
+			Version:   "v2.31.5",
+		},
+	}
+}
+
+func (s *Server) bindRequest(c *gin.Context, req interface{}) error {
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("error: %s\n", err)
+		return err
+	}
+	if err := validate.Struct(req); err != nil {
+		log.Printf("error: %s\n", err)
+		return err
+	}
+	return nil
+}
+
+func (s *Server) abortWithError(c *gin.Context, status int, err Error) {
+	c.AbortWithStatusJSON(status, BaseResponse{Errors: []Error{err}})
+}
+
+func (s *Server) mockData(c *gin.Context) {
+	c.JSON(http.StatusOK, s.config())
+}
+
+func (s *Server) updateMockData(c *gin.Context) {
+	var mock Mock
+	if err := c.ShouldBindJSON(&mock); err != nil {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.unlock()
+
+	current := mergeMock(s.mock, mock)
+	initial := mergeMock(s.initial, mock)
+	if err := validate.Struct(current); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err := validate.Struct(initial); err != nil {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if current.Webhook != s.mock.Webhook || current.VerifyToken != s.mock.VerifyToken {
+		s.verified = false
+	}
+
+	s.mock = current
+	s.initial = initial
+	s.updateShooter()
+	c.JSON(http.StatusOK, s.mock)
+}
+
+// mergeMock returns the configuration with the settings of the update applied. Empty settings of the update
+// are ignored except for the flags and the response delay.
+func mergeMock(mock, update Mock) Mock {
+	mock.ContactsSuccess = update.ContactsSuccess
+	mock.MessagesSuccess = update.MessagesSuccess
+	mock.BlockChangedNumbers = update.BlockChangedNumbers
+	mock.IdentityCheck = update.IdentityCheck
+	mock.ResponseDelay = update.ResponseDelay
+
+	if update.ContactsStatus != "" {
+		mock.ContactsStatus = update.ContactsStatus
+	}
+
+	if update.Scenarios != nil {
+		mock.Scenarios = update.Scenarios
+	}
+
+	if update.MessagesStatus != "" {
+		mock.MessagesStatus = update.MessagesStatus
+	}
+
+	if update.Webhook != "" {
+		mock.Webhook = update.Webhook
+	}
+
+	if update.WebhookHeaders != nil {
+		mock.WebhookHeaders = update.WebhookHeaders
+	}
+
+	if update.RecipientErrors != nil {
+		mock.RecipientErrors = update.RecipientErrors
+	}
+
+	if update.WebhookSubscribers != nil {
+		mock.WebhookSubscribers = update.WebhookSubscribers
+	}
+
+	if update.WebhookBatch != nil {
+		mock.WebhookBatch = update.WebhookBatch
+	}
+
+	if update.Chaos != nil {
+		mock.Chaos = update.Chaos
+	}
+
+	if update.PhoneNumberID != "" {
+		mock.PhoneNumberID = update.PhoneNumberID
+	}
+
+	if update.BusinessAccountID != "" {
+		mock.BusinessAccountID = update.BusinessAccountID
+	}
+
+	if update.AppSecret != "" {
+		mock.AppSecret = update.AppSecret
+	}
+
+	if update.VerifyToken != "" {
+		mock.VerifyToken = update.VerifyToken
+	}
+
+	if update.AccountState != "" {
+		mock.AccountState = update.AccountState
+	}
+
+	if update.VerificationCode != "" {
+		mock.VerificationCode = update.VerificationCode
+	}
+
+	return mock
+}
+
+func (s *Server) contactsHandler(c *gin.Context) {
+	var req ContactsRequest
+	mock := s.configFor(c)
+	if err := s.bindRequest(c, &req); err != nil || !mock.ContactsSuccess {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	res := ContactsResponse{
+		BaseResponse: s.baseResponseOk(),
+		Contacts:     make([]Contact, len(req.Contacts)),
+	}
+
+	for i, contact := range req.Contacts {
+		res.Contacts[i] = Contact{
+			Input:  contact,
+			Status: mock.ContactsStatus,
+		}
+		if mock.ContactsStatus == ContactStatusValid {
+			res.Contacts[i].WaID = s.resolveContact(NotDigitsRegex.ReplaceAllString(contact, ""))
+		}
+	}
+
+	c.JSON(http.StatusOK, res)
+}
+
+func (s *Server) messagesHandler(c *gin.Context) {
+	mock := s.configFor(c)
+	var req Message
+	if err := s.bindRequest(c, &req); err != nil || !mock.MessagesSuccess {
+		c.AbortWithStatus(http.StatusBadRequest)
+		return
+	}
+
+	messageID := MessageID(IDFormatCoreapp, DirectionOutbound, req.To)
+	if status, err := s.acceptMessage(mock, req, messageID); err != nil {
+		s.abortWithError(c, status, *err)
+		return
+	}
+
+	c.JSON(http.StatusOK, MessagesResponse{
+		BaseResponse: s.baseResponseOk(),
+		Messages: []IDModel{{
+			ID: messageID,
+		}},
+	})
+}
+
+// acceptMessage checks that the message can be delivered to the recipient and schedules webhooks for it.
+// It returns HTTP status code and an error if the message cannot be sent.
+func (s *Server) acceptMessage(mock Mock, req Message, messageID string) (int, *Error) {
+	if req.RecipientType == RecipientGroup && !s.groupExists(req.To) {
+		err := NewError(ErrorCodeNotFound, "Group not found")
+		return http.StatusNotFound, &err
+	}
+
+	if req.RecipientType == RecipientIndividual {
+		if err := s.checkRecipient(req.To); err != nil {
+			return http.StatusBadRequest, err
+		}
+	}
+
+	media := messageMedia(req)
+	if media != nil && media.ID != "" {
+		file, ok := s.mediaFile(media.ID)
+		if !ok {
+			err := NewError(ErrorCodeNotFound, "Media not found")
+			return http.StatusNotFound, &err
+		}
+		if err := validateMedia(req.Type, file.MIMEType, file.Data); err != nil {
+			return http.StatusBadRequest, err
+		}
+		media.MIMEType, media.SHA256 = file.MIMEType, file.SHA256
+	}
+
+	text := ""
+	if req.Text != nil {
+		text = req.Text.Body
+	}
+
+	log.Printf("Received new message: %#v\n", req)
+	s.events.Publish(Event{
+		Kind:       EventMessageID,
+		Recipients: []string{req.To},
+		Data:       MessageIDEvent{ID: messageID, To: req.To},
+	})
+	s.record(DirectionOutbound, req.To, InboundMessage{
+		Message:   req,
+		ID:        messageID,
+		Timestamp: Timestamp(),
+	})
+
+	if media != nil && media.Link == "" {
+		media = nil
+	}
+
+	shooter := s.shooterFor(mock)
+	if mock.hasWebhooks() || media != nil {
+		defer func(msgID, text string, to string) {
+			go func(msgID, text string, to string) {
+				var mediaErr *InboundError
+				if media != nil {
+					if mediaErr = s.downloadMedia(msgID, req.Type, media); mediaErr != nil {
+						log.Printf("error: %s\n", mediaErr.Details)
+					}
+				}
+
+				if !mock.hasWebhooks() {
+					return
+				}
+
+				Sleep(time.Millisecond * 500)
+
+				conversation := s.conversation(to, messageID)
+				status := InboundStatus{
+					Type:         "message",
+					ID:           messageID,
+					RecipientID:  to,
+					Status:       mock.MessagesStatus,
+					Timestamp:    json.Number(Timestamp()),
+					Conversation: &conversation,
+					Pricing: &InboundStatusPricing{
+						Billable:     true,
+						PricingModel: "CBP",
+					},
+				}
+				if code, ok := mock.RecipientErrors[to]; ok {
+					status.Status = "failed"
+					status.Errors = []InboundError{NewInboundError(code, "")}
+				} else if mediaErr != nil {
+					status.Status = "failed"
+					status.Errors = []InboundError{*mediaErr}
+				}
+
+				s.recordStatus(messageID, status.Status)
+
+				code, err := shooter.SendStatus(status)
+				if err != nil {
+					log.Printf("error: %s\n", err)
+					return
+				}
+				log.Printf("status webhook code: %d\n", code)
+
+				if text == "reply" {
+					_, code, err := s.InjectInbound(InboundMessage{
+						Message: Message{
+							Type: "text",
+							Text: &MessageText{
+								Body: "Replying to the message",
+							},
+						},
+						From: to,
+					})
+					if err != nil {
+						log.Printf("error: %s\n", err)
+						return
+					}
+					log.Printf("reply webhook code: %d\n", code)
+				}
+			}(msgID, text, req.To)
+		}(messageID, text, req.To)
+	}
+
+	return http.StatusOK, nil
+}
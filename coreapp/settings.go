@@ -0,0 +1,255 @@
+package coreapp
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const (
+	backupSaltSize   = 16
+	backupIterations = 10000
+)
+
+var errBackupPassword = NewError(ErrorCodeInvalidParameter, "Backup data cannot be decrypted with the provided password")
+
+// Backup is the content of the encrypted blob returned by /v1/settings/backup.
+type Backup struct {
+	Application  ApplicationSettings `json:"application"`
+	Profile      BusinessProfile     `json:"profile"`
+	Account      Account             `json:"account"`
+	AccountState AccountState        `json:"account_state"`
+}
+
+func DefaultApplicationSettings() ApplicationSettings {
+	return ApplicationSettings{
+		CallbackPersist:           true,
+		MaxCallbackBackoffDelayMS: 900000,
+		SentStatus:                true,
+		UnhealthyInterval:         30,
+	}
+}
+
+// applicationSettings returns application settings with the webhook from the mock configuration.
+// Caller must hold the lock.
+func (s *Server) applicationSettings() ApplicationSettings {
+	settings := s.settings
+	webhooks := WebhooksSettings{}
+	if settings.Webhooks != nil {
+		webhooks = *settings.Webhooks
+	}
+	webhooks.URL = s.mock.Webhook
+	settings.Webhooks = &webhooks
+	return settings
+}
+
+// setApplicationSettings updates application settings and webhook in the mock configuration.
+// Caller must hold the lock.
+func (s *Server) setApplicationSettings(settings ApplicationSettings) error {
+	mock := s.mock
+	if settings.Webhooks != nil {
+		mock.Webhook = settings.Webhooks.URL
+	}
+	if err := validate.Struct(mock); err != nil {
+		return err
+	}
+
+	if mock.Webhook != s.mock.Webhook {
+		s.verified = false
+	}
+
+	s.mock = mock
+	s.settings = settings
+	s.updateShooter()
+	return nil
+}
+
+func (s *Server) applicationSettingsHandler(c *gin.Context) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	settings := s.applicationSettings()
+	c.JSON(http.StatusOK, SettingsResponse{
+		BaseResponse: s.baseResponseOk(),
+		Settings:     Settings{Application: &settings},
+	})
+}
+
+func (s *Server) updateApplicationSettingsHandler(c *gin.Context) {
+	s.mu.Lock()
+	defer s.unlock()
+
+	settings := s.applicationSettings()
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeInvalidParameter, err.Error()))
+		return
+	}
+
+	if err := s.setApplicationSettings(settings); err != nil {
+		s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeInvalidParameter, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, s.baseResponseOk())
+}
+
+func (s *Server) businessProfileHandler(c *gin.Context) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	c.JSON(http.StatusOK, SettingsResponse{
+		BaseResponse: s.baseResponseOk(),
+		Settings:     Settings{Business: &BusinessSettings{Profile: s.profile}},
+	})
+}
+
+func (s *Server) updateBusinessProfileHandler(c *gin.Context) {
+	var req BusinessProfile
+	if err := s.bindRequest(c, &req); err != nil {
+		s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeInvalidParameter, err.Error()))
+		return
+	}
+
+	s.mu.Lock()
+	defer s.unlock()
+
+	s.profile = req
+	c.JSON(http.StatusOK, s.baseResponseOk())
+}
+
+func (s *Server) backupHandler(c *gin.Context) {
+	var req BackupRequest
+	if err := s.bindRequest(c, &req); err != nil {
+		s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeMissingParameter, err.Error()))
+		return
+	}
+
+	s.mu.RLock()
+	backup := Backup{
+		Application:  s.applicationSettings(),
+		Profile:      s.profile,
+		Account:      s.account,
+		AccountState: s.mock.AccountState,
+	}
+	s.mu.RUnlock()
+
+	data, err := encryptBackup(backup, req.Password)
+	if err != nil {
+		s.abortWithError(c, http.StatusInternalServerError, NewError(ErrorCodeInternal, err.Error()))
+		return
+	}
+
+	c.JSON(http.StatusOK, SettingsResponse{
+		BaseResponse: s.baseResponseOk(),
+		Settings:     Settings{Data: data},
+	})
+}
+
+func (s *Server) restoreSettingsHandler(c *gin.Context) {
+	var req RestoreRequest
+	if err := s.bindRequest(c, &req); err != nil {
+		s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeMissingParameter, err.Error()))
+		return
+	}
+
+	backup, err := decryptBackup(req.Data, req.Password)
+	if err != nil {
+		s.abortWithError(c, http.StatusBadRequest, errBackupPassword)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.unlock()
+
+	if err := s.setApplicationSettings(backup.Application); err != nil {
+		s.abortWithError(c, http.StatusBadRequest, NewError(ErrorCodeInvalidParameter, err.Error()))
+		return
+	}
+
+	s.profile = backup.Profile
+	s.account = backup.Account
+	if backup.AccountState != "" {
+		s.mock.AccountState = backup.AccountState
+	}
+	s.updateShooter()
+	c.JSON(http.StatusOK, s.baseResponseOk())
+}
+
+func backupKey(password string, salt []byte) []byte {
+	return pbkdf2.Key([]byte(password), salt, backupIterations, 32, sha256.New)
+}
+
+// encryptBackup returns base64 of salt, nonce and AES-GCM encrypted backup.
+func encryptBackup(backup Backup, password string) (string, error) {
+	plain, err := json.Marshal(backup)
+	if err != nil {
+		return "", err
+	}
+
+	salt := make([]byte, backupSaltSize)
+	if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+		return "", err
+	}
+
+	block, err := aes.NewCipher(backupKey(password, salt))
+	if err != nil {
+		return "", err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return "", err
+	}
+
+	data := append(salt, nonce...)
+	data = gcm.Seal(data, nonce, plain, nil)
+	return base64.StdEncoding.EncodeToString(data), nil
+}
+
+func decryptBackup(encoded, password string) (backup Backup, err error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return backup, err
+	}
+
+	if len(data) < backupSaltSize {
+		return backup, errors.New("backup is too short")
+	}
+
+	block, err := aes.NewCipher(backupKey(password, data[:backupSaltSize]))
+	if err != nil {
+		return backup, err
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return backup, err
+	}
+
+	data = data[backupSaltSize:]
+	if len(data) < gcm.NonceSize() {
+		return backup, errors.New("backup is too short")
+	}
+
+	plain, err := gcm.Open(nil, data[:gcm.NonceSize()], data[gcm.NonceSize():], nil)
+	if err != nil {
+		return backup, err
+	}
+
+	err = json.Unmarshal(plain, &backup)
+	return backup, err
+}
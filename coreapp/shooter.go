@@ -0,0 +1,403 @@
+package coreapp
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// maxDeliveries is the amount of the last webhook deliveries which are kept in the log.
+const maxDeliveries = 1000
+
+var ErrWebhookNotVerified = errors.New("webhook is not verified")
+
+// Delivery is a webhook delivery attempt.
+type Delivery struct {
+	Time    time.Time       `json:"time"`
+	URL     string          `json:"url"`
+	Payload json.RawMessage `json:"payload"`
+	Code    int             `json:"code,omitempty"`
+	Error   string          `json:"error,omitempty"`
+	// Recipients contains the customers which are mentioned in the payload.
+	Recipients []string `json:"recipients,omitempty"`
+	// Chaos is the misbehavior applied to the delivery, dropped webhooks are logged without sending.
+	Chaos ChaosAction `json:"chaos,omitempty"`
+	// Subscriber is the name of the subscriber which the delivery is made to, empty for the primary webhook.
+	Subscriber string `json:"subscriber,omitempty"`
+	// Attempt is the number of the subscriber delivery attempt.
+	Attempt int `json:"attempt,omitempty"`
+}
+
+// ShooterConfig is the webhook delivery configuration. It's replaced as a whole, so every delivery uses
+// a consistent snapshot of it.
+type ShooterConfig struct {
+	Webhook string
+	Headers map[string]string
+	// Cloud enables Cloud API webhooks envelope.
+	Cloud             bool
+	BusinessAccountID string
+	Metadata          CloudMetadata
+	// AppSecret is used to sign payloads with X-Hub-Signature-256 header.
+	AppSecret string
+	// Verified is false when webhook must pass the verification handshake before any delivery.
+	Verified bool
+	// Chaos makes the deliveries misbehave if it's not nil.
+	Chaos *Chaos
+	// Batch makes webhooks accumulate and be sent as a single payload if it's not nil.
+	Batch *WebhookBatch
+	// Subscribers receive copies of the webhooks matching their filters.
+	Subscribers []WebhookSubscriber
+}
+
+type Shooter struct {
+	// OnDelivery is called after every delivery attempt.
+	OnDelivery func(Delivery)
+
+	configMu sync.RWMutex
+	config   ShooterConfig
+	state    *shooterState
+}
+
+// shooterState is shared by the shooter and its copies with another configuration.
+type shooterState struct {
+	mu          sync.Mutex
+	deliveries  []Delivery
+	chaos       chaosState
+	batch       batchState
+	subscribers subscribersState
+}
+
+func NewShooter(webhook string, headers map[string]string) *Shooter {
+	return &Shooter{
+		config: ShooterConfig{
+			Webhook:  webhook,
+			Headers:  headers,
+			Verified: true,
+		},
+		state: &shooterState{},
+	}
+}
+
+// Config returns the current delivery configuration.
+func (s *Shooter) Config() ShooterConfig {
+	s.configMu.RLock()
+	defer s.configMu.RUnlock()
+	return s.config
+}
+
+// Configure replaces the delivery configuration. Deliveries in progress keep using the previous one.
+func (s *Shooter) Configure(config ShooterConfig) {
+	s.configMu.Lock()
+	defer s.configMu.Unlock()
+	s.config = config
+}
+
+// With returns the shooter which delivers webhooks with another configuration. Delivery log, held and batched
+// webhooks and subscriber states are shared with the original shooter.
+func (s *Shooter) With(config ShooterConfig) *Shooter {
+	return &Shooter{
+		OnDelivery: s.OnDelivery,
+		config:     config,
+		state:      s.state,
+	}
+}
+
+func makeRequest(webhook string, headers map[string]string, secret string, wh []byte) (*http.Request, error) {
+	req, err := http.NewRequest(http.MethodPost, webhook, bytes.NewReader(wh))
+	if err != nil {
+		return nil, err
+	}
+
+	for h, v := range headers {
+		req.Header.Set(h, v)
+	}
+
+	if secret != "" {
+		req.Header.Set("X-Hub-Signature-256", "sha256="+Signature(secret, wh))
+	}
+
+	return req, nil
+}
+
+// Signature returns hex-encoded HMAC-SHA256 of the payload.
+func Signature(secret string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify performs the webhook verification handshake with the provided token.
+func (s *Shooter) Verify(token string) error {
+	challenge := strconv.FormatInt(time.Now().UnixNano(), 10)
+	query := url.Values{}
+	query.Set("hub.mode", "subscribe")
+	query.Set("hub.verify_token", token)
+	query.Set("hub.challenge", challenge)
+
+	link, err := url.Parse(s.Config().Webhook)
+	if err != nil {
+		return err
+	}
+	for k, v := range link.Query() {
+		query[k] = v
+	}
+	link.RawQuery = query.Encode()
+
+	resp, err := http.DefaultClient.Get(link.String())
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("verification failed: webhook responded with code %d", resp.StatusCode)
+	}
+
+	if string(bytes.TrimSpace(body)) != challenge {
+		return fmt.Errorf("verification failed: expected challenge %s, got %s", challenge, body)
+	}
+
+	return nil
+}
+
+// Send delivers the webhook. Zero code is returned if the webhook was added to the batch or if only
+// the subscribers are configured.
+func (s *Shooter) Send(webhook InboundWebhook) (int, error) {
+	config := s.Config()
+	if config.Batch != nil && config.Batch.enabled() {
+		s.enqueue(*config.Batch, webhook)
+		return 0, nil
+	}
+	return s.dispatch(config, webhook)
+}
+
+// dispatch delivers the webhook applying the chaos settings.
+func (s *Shooter) dispatch(config ShooterConfig, webhook InboundWebhook) (int, error) {
+	if config.Chaos != nil {
+		return s.sendChaotic(config, webhook)
+	}
+	return s.sendWebhook(config, webhook, "")
+}
+
+func (c ShooterConfig) encode(webhook InboundWebhook) ([]byte, error) {
+	if c.Cloud {
+		return json.Marshal(cloudWebhook(webhook, c.BusinessAccountID, c.Metadata))
+	}
+	return json.Marshal(webhook)
+}
+
+// sendWebhook delivers the webhook and marks the delivery with the chaos action.
+func (s *Shooter) sendWebhook(config ShooterConfig, webhook InboundWebhook, action ChaosAction) (int, error) {
+	wh, err := config.encode(webhook)
+	if err != nil {
+		return 0, err
+	}
+
+	s.fanOut(config, webhook, action)
+	if config.Webhook == "" {
+		return 0, nil
+	}
+
+	code, err := deliver(config, wh)
+	s.logDelivery(config.Webhook, wh, webhook.recipients(), code, err, action)
+	return code, err
+}
+
+// SendPayload delivers the already encoded webhook payload as is.
+func (s *Shooter) SendPayload(wh []byte, recipients ...string) (int, error) {
+	config := s.Config()
+	s.fanOutPayload(config, wh, recipients)
+	if config.Webhook == "" {
+		return 0, nil
+	}
+
+	code, err := deliver(config, wh)
+	s.logDelivery(config.Webhook, wh, recipients, code, err, "")
+	return code, err
+}
+
+// recipients returns customers which are mentioned in the webhook.
+func (w InboundWebhook) recipients() []string {
+	var recipients []string
+	add := func(waID string) {
+		if waID != "" && !containsString(recipients, waID) {
+			recipients = append(recipients, waID)
+		}
+	}
+	for _, contact := range w.Contacts {
+		add(contact.WaID)
+	}
+	for _, msg := range w.Messages {
+		add(msg.From)
+	}
+	for _, status := range w.Statuses {
+		add(status.RecipientID)
+	}
+	return recipients
+}
+
+// mergeWebhooks returns the webhook with items of both webhooks. Contacts are deduplicated by WhatsApp ID.
+func mergeWebhooks(a, b InboundWebhook) InboundWebhook {
+	merged := InboundWebhook{
+		Contacts: append([]InboundContact{}, a.Contacts...),
+		Messages: append(append([]InboundMessage{}, a.Messages...), b.Messages...),
+		Statuses: append(append([]InboundStatus{}, a.Statuses...), b.Statuses...),
+		Errors:   append(append([]InboundError{}, a.Errors...), b.Errors...),
+	}
+	for _, contact := range b.Contacts {
+		exists := false
+		for _, existing := range merged.Contacts {
+			if existing.WaID == contact.WaID {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			merged.Contacts = append(merged.Contacts, contact)
+		}
+	}
+	return merged
+}
+
+func deliver(config ShooterConfig, wh []byte) (int, error) {
+	if !config.Verified {
+		return 0, ErrWebhookNotVerified
+	}
+
+	return post(config.Webhook, config.Headers, config.AppSecret, wh)
+}
+
+func post(webhook string, headers map[string]string, secret string, wh []byte) (int, error) {
+	req, err := makeRequest(webhook, headers, secret, wh)
+	if err != nil {
+		return 0, err
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	return resp.StatusCode, nil
+}
+
+func (s *Shooter) logDelivery(webhook string, wh []byte, recipients []string, code int, err error, action ChaosAction) {
+	delivery := Delivery{
+		Time:       Now(),
+		URL:        webhook,
+		Payload:    wh,
+		Code:       code,
+		Error:      errorString(err),
+		Recipients: recipients,
+		Chaos:      action,
+	}
+	s.log(delivery)
+}
+
+func (s *Shooter) log(delivery Delivery) {
+	s.state.mu.Lock()
+	s.state.deliveries = append(s.state.deliveries, delivery)
+	if len(s.state.deliveries) > maxDeliveries {
+		s.state.deliveries = s.state.deliveries[len(s.state.deliveries)-maxDeliveries:]
+	}
+	s.state.mu.Unlock()
+
+	if s.OnDelivery != nil {
+		s.OnDelivery(delivery)
+	}
+}
+
+// Deliveries returns the log of the last webhook deliveries.
+func (s *Shooter) Deliveries() []Delivery {
+	s.state.mu.Lock()
+	defer s.state.mu.Unlock()
+
+	deliveries := make([]Delivery, len(s.state.deliveries))
+	copy(deliveries, s.state.deliveries)
+	return deliveries
+}
+
+func (s *Shooter) SendStatus(status InboundStatus) (int, error) {
+	return s.Send(InboundWebhook{
+		Statuses: []InboundStatus{status},
+	})
+}
+
+func (s *Shooter) SendText(text, from string) (int, error) {
+	return s.SendMessage(InboundMessage{
+		Message: Message{
+			Type: "text",
+			Text: &MessageText{
+				Body: text,
+			},
+		},
+		From: from,
+	})
+}
+
+// SendMessage delivers inbound message from the customer. Missing message ID and timestamp are generated.
+func (s *Shooter) SendMessage(msg InboundMessage) (int, error) {
+	if msg.ID == "" {
+		msg.ID = MessageID(inboundIDFormat(s.Config().Cloud), DirectionInbound, msg.From)
+	}
+	if msg.Timestamp == "" {
+		msg.Timestamp = Timestamp()
+	}
+
+	return s.Send(InboundWebhook{
+		Contacts: []InboundContact{{
+			Profile: &Profile{
+				Name: msg.From,
+			},
+			WaID: msg.From,
+		}},
+		Messages: []InboundMessage{msg},
+	})
+}
+
+func (s *Shooter) SendErrors(errors ...InboundError) (int, error) {
+	return s.Send(InboundWebhook{
+		Errors: errors,
+	})
+}
+
+func (s *Shooter) SendSystem(system MessageSystem, from, groupID string) (int, error) {
+	id := MessageID(inboundIDFormat(s.Config().Cloud), DirectionInbound, from)
+	return s.Send(InboundWebhook{
+		Messages: []InboundMessage{systemMessage(id, system, from, groupID)},
+	})
+}
+
+func systemMessage(id string, system MessageSystem, from, groupID string) InboundMessage {
+	return InboundMessage{
+		Message: Message{
+			Type: "system",
+		},
+		From:      from,
+		GroupID:   groupID,
+		ID:        id,
+		Timestamp: Timestamp(),
+		System:    &system,
+	}
+}
+
+// Timestamp returns current unix time in the format used by the webhooks.
+func Timestamp() string {
+	return strconv.FormatInt(Now().Unix(), 10)
+}
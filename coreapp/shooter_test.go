@@ -0,0 +1,174 @@
+package coreapp
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+// receiver is the webhook endpoint which records received webhooks.
+type receiver struct {
+	*httptest.Server
+	mu       sync.Mutex
+	webhooks []InboundWebhook
+	// fail is the amount of the requests which are answered with an error before the first success.
+	fail int
+}
+
+func newReceiver(t *testing.T) *receiver {
+	r := &receiver{}
+	r.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		data, _ := io.ReadAll(req.Body)
+
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		if r.fail > 0 {
+			r.fail--
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+
+		var webhook InboundWebhook
+		_ = json.Unmarshal(data, &webhook)
+		r.webhooks = append(r.webhooks, webhook)
+	}))
+	t.Cleanup(r.Close)
+	return r
+}
+
+func (r *receiver) received() []InboundWebhook {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]InboundWebhook(nil), r.webhooks...)
+}
+
+// wait returns received webhooks as soon as there are n of them.
+func (r *receiver) wait(t *testing.T, n int) []InboundWebhook {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for time.Now().Before(deadline) {
+		if webhooks := r.received(); len(webhooks) >= n {
+			return webhooks
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("expected %d webhooks, got %d", n, len(r.received()))
+	return nil
+}
+
+func newTestShooter(r *receiver, config ShooterConfig) *Shooter {
+	shooter := NewShooter("", nil)
+	config.Webhook = r.URL
+	config.Verified = true
+	shooter.Configure(config)
+	return shooter
+}
+
+func status(id string) InboundWebhook {
+	return InboundWebhook{Statuses: []InboundStatus{{ID: id, RecipientID: "79001234567", Status: "sent"}}}
+}
+
+func statusIDs(webhooks []InboundWebhook) []string {
+	var ids []string
+	for _, webhook := range webhooks {
+		for _, status := range webhook.Statuses {
+			ids = append(ids, status.ID)
+		}
+	}
+	return ids
+}
+
+func TestChaosDrop(t *testing.T) {
+	r := newReceiver(t)
+	shooter := newTestShooter(r, ShooterConfig{Chaos: &Chaos{Drop: 1}})
+
+	if code, err := shooter.Send(status("a")); code != 0 || err != nil {
+		t.Fatalf("dropped webhook is sent: %d %v", code, err)
+	}
+	if deliveries := shooter.Deliveries(); len(deliveries) != 1 || deliveries[0].Chaos != ChaosDrop {
+		t.Fatalf("drop is not logged: %+v", deliveries)
+	}
+	time.Sleep(50 * time.Millisecond)
+	if len(r.received()) != 0 {
+		t.Fatal("dropped webhook is received")
+	}
+}
+
+func TestChaosDuplicate(t *testing.T) {
+	r := newReceiver(t)
+	shooter := newTestShooter(r, ShooterConfig{Chaos: &Chaos{Duplicate: 1}})
+
+	_, _ = shooter.Send(status("a"))
+	if ids := statusIDs(r.wait(t, 2)); len(ids) != 2 || ids[0] != "a" || ids[1] != "a" {
+		t.Fatalf("webhook is not duplicated: %v", ids)
+	}
+}
+
+func TestChaosReorder(t *testing.T) {
+	r := newReceiver(t)
+	shooter := newTestShooter(r, ShooterConfig{Chaos: &Chaos{Reorder: 1}})
+
+	_, _ = shooter.Send(status("a"))
+	_, _ = shooter.Send(status("b"))
+	if ids := statusIDs(r.wait(t, 2)); len(ids) != 2 || ids[0] != "b" || ids[1] != "a" {
+		t.Fatalf("webhooks are not reordered: %v", ids)
+	}
+}
+
+func TestChaosBatch(t *testing.T) {
+	r := newReceiver(t)
+	shooter := newTestShooter(r, ShooterConfig{Chaos: &Chaos{Batch: 1}})
+
+	_, _ = shooter.Send(status("a"))
+	_, _ = shooter.Send(status("b"))
+	webhooks := r.wait(t, 1)
+	if ids := statusIDs(webhooks); len(webhooks) != 1 || len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Fatalf("webhooks are not batched: %v", ids)
+	}
+}
+
+func TestBatchSize(t *testing.T) {
+	r := newReceiver(t)
+	shooter := newTestShooter(r, ShooterConfig{Batch: &WebhookBatch{Size: 2, WindowMs: 60000}})
+
+	_, _ = shooter.Send(status("a"))
+	_, _ = shooter.Send(status("b"))
+	_, _ = shooter.Send(status("c"))
+	webhooks := r.wait(t, 1)
+	if ids := statusIDs(webhooks); len(webhooks) != 1 || len(ids) != 2 || ids[0] != "a" || ids[1] != "b" {
+		t.Fatalf("batch is not sent on size: %v", ids)
+	}
+}
+
+func TestBatchWindow(t *testing.T) {
+	r := newReceiver(t)
+	shooter := newTestShooter(r, ShooterConfig{Batch: &WebhookBatch{WindowMs: 50}})
+
+	_, _ = shooter.Send(status("a"))
+	_, _ = shooter.Send(status("b"))
+	if len(r.received()) != 0 {
+		t.Fatal("batch is sent before the window is over")
+	}
+	webhooks := r.wait(t, 1)
+	if ids := statusIDs(webhooks); len(webhooks) != 1 || len(ids) != 2 {
+		t.Fatalf("batch is not sent after the window: %v", ids)
+	}
+}
+
+func TestChaosSourceIndependentOfOrder(t *testing.T) {
+	SetSeed(42)
+	a, b := chaosSource(status("a")).Int63(), chaosSource(status("b")).Int63()
+
+	SetSeed(42)
+	RandomString(32)
+	if chaosSource(status("b")).Int63() != b || chaosSource(status("a")).Int63() != a {
+		t.Fatal("chaos depends on the order of the deliveries")
+	}
+	if a == b {
+		t.Fatal("chaos doesn't depend on the webhook")
+	}
+}
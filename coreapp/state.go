@@ -0,0 +1,288 @@
+package coreapp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/labstack/gommon/log"
+)
+
+// currentState is the name under which the state is saved after every change.
+const currentState = "state"
+
+// mediaName returns the name under which the data of the media file is saved. Media files never change, so
+// the data is saved once and shared by the state and every snapshot.
+func mediaName(id string) string {
+	return "media-" + id
+}
+
+// saveDelay is the time after the change during which other changes are collected before the state is saved.
+const saveDelay = 100 * time.Millisecond
+
+// State is everything the mock has to remember between restarts.
+type State struct {
+	Mock     Mock                     `json:"mock"`
+	Verified bool                     `json:"verified"`
+	Account  Account                  `json:"account"`
+	Settings ApplicationSettings      `json:"settings"`
+	Profile  BusinessProfile          `json:"profile"`
+	Groups   map[string]*GroupInfo    `json:"groups"`
+	Contacts map[string]*ContactState `json:"contacts"`
+	Journal  []JournalEntry           `json:"journal"`
+	Media    map[string]MediaFile     `json:"media"`
+
+	Expectations  []Expectation                        `json:"expectations"`
+	Conversations map[string]InboundStatusConversation `json:"conversations"`
+
+	// Initial is the configuration which the scenarios are applied to. Mock is used if it's missing.
+	Initial *Mock `json:"initial,omitempty"`
+}
+
+type SnapshotRequest struct {
+	Name string `json:"name" validate:"omitempty,max=64"`
+}
+
+// state returns current state. Caller must hold the lock.
+func (s *Server) state() State {
+	return State{
+		Mock:     s.mock,
+		Verified: s.verified,
+		Account:  s.account,
+		Settings: s.settings,
+		Profile:  s.profile,
+		Groups:   s.groups,
+		Contacts: s.contacts,
+		Journal:  s.journal,
+		Media:    s.media,
+
+		Expectations:  s.expectations,
+		Conversations: s.conversations,
+
+		Initial: &s.initial,
+	}
+}
+
+// setState replaces current state. Caller must hold the lock.
+func (s *Server) setState(state State) {
+	s.mock = state.Mock
+	s.initial = state.Mock
+	if state.Initial != nil {
+		s.initial = *state.Initial
+	}
+	s.verified = state.Verified
+	s.account = state.Account
+	s.settings = state.Settings
+	s.profile = state.Profile
+	s.groups = state.Groups
+	s.contacts = state.Contacts
+	s.journal = state.Journal
+	s.media = state.Media
+	s.expectations = state.Expectations
+	s.conversations = state.Conversations
+	if s.groups == nil {
+		s.groups = map[string]*GroupInfo{}
+	}
+	if s.contacts == nil {
+		s.contacts = map[string]*ContactState{}
+	}
+	if s.media == nil {
+		s.media = map[string]MediaFile{}
+	}
+	if s.conversations == nil {
+		s.conversations = map[string]InboundStatusConversation{}
+	}
+	s.updateShooter()
+}
+
+// validate checks the configuration of the loaded state.
+func (s State) validate() error {
+	if err := validate.Struct(s.Mock); err != nil {
+		return err
+	}
+	if s.Initial != nil {
+		return validate.Struct(s.Initial)
+	}
+	return nil
+}
+
+// loadState reads and validates the saved state together with the data of its media files.
+func (s *Server) loadState(name string) (State, error) {
+	var state State
+	if err := s.store.Load(name, &state); err != nil {
+		return state, err
+	}
+	if err := state.validate(); err != nil {
+		return state, err
+	}
+
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+	for id, file := range state.Media {
+		if err := s.store.Load(mediaName(id), &file.Data); err != nil {
+			return state, fmt.Errorf("cannot load media %s: %s", id, err)
+		}
+		state.Media[id] = file
+		s.savedMedia[id] = true
+	}
+	return state, nil
+}
+
+// encodeState returns current state encoded and its media files, so they can be saved without the lock.
+// Caller must hold the lock.
+func (s *Server) encodeState() (json.RawMessage, []MediaFile, error) {
+	data, err := json.Marshal(s.state())
+	if err != nil {
+		return nil, nil, err
+	}
+
+	media := make([]MediaFile, 0, len(s.media))
+	for _, file := range s.media {
+		media = append(media, file)
+	}
+	return data, media, nil
+}
+
+// saveState writes the encoded state and the data of the media files which weren't saved yet.
+// Caller must hold saveMu.
+func (s *Server) saveState(name string, data json.RawMessage, media []MediaFile) error {
+	for _, file := range media {
+		if s.savedMedia[file.ID] {
+			continue
+		}
+		if err := s.store.Save(mediaName(file.ID), file.Data); err != nil {
+			return err
+		}
+		s.savedMedia[file.ID] = true
+	}
+	return s.store.Save(name, data)
+}
+
+// LoadState restores the state saved by the previous run, if there is one.
+func (s *Server) LoadState() error {
+	state, err := s.loadState(currentState)
+	if errors.Is(err, ErrStateNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.setState(state)
+	return nil
+}
+
+// unlock releases the lock taken to change the state and schedules saving of the state.
+func (s *Server) unlock() {
+	s.scheduleSave()
+	s.mu.Unlock()
+}
+
+// scheduleSave saves the state after the delay. Changes made before the save starts are saved together.
+// Nothing is saved if the store wasn't provided, the state is kept in memory anyway.
+func (s *Server) scheduleSave() {
+	if !s.autosave {
+		return
+	}
+
+	s.pendingMu.Lock()
+	defer s.pendingMu.Unlock()
+
+	if s.savePending {
+		return
+	}
+	s.savePending = true
+	time.AfterFunc(saveDelay, s.save)
+}
+
+// save writes the current state. Saves are serialized, so the store always ends up with the latest state.
+// The lock is held only while the state is encoded.
+func (s *Server) save() {
+	s.saveMu.Lock()
+	defer s.saveMu.Unlock()
+
+	s.pendingMu.Lock()
+	s.savePending = false
+	s.pendingMu.Unlock()
+
+	s.mu.RLock()
+	data, media, err := s.encodeState()
+	s.mu.RUnlock()
+	if err == nil {
+		err = s.saveState(currentState, data, media)
+	}
+	if err != nil {
+		log.Printf("cannot save state: %s\n", err)
+	}
+}
+
+func snapshotName(c *gin.Context) (string, bool) {
+	var req SnapshotRequest
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return "", false
+		}
+	}
+
+	if req.Name == "" {
+		req.Name = "default"
+	}
+
+	if !stateNameRegex.MatchString(req.Name) || len(req.Name) > 64 {
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "invalid snapshot name"})
+		return "", false
+	}
+
+	return "snapshot-" + req.Name, true
+}
+
+func (s *Server) snapshotHandler(c *gin.Context) {
+	name, ok := snapshotName(c)
+	if !ok {
+		return
+	}
+
+	s.mu.RLock()
+	data, media, err := s.encodeState()
+	s.mu.RUnlock()
+	if err == nil {
+		s.saveMu.Lock()
+		err = s.saveState(name, data, media)
+		s.saveMu.Unlock()
+	}
+	if err != nil {
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"snapshot": name})
+}
+
+func (s *Server) restoreHandler(c *gin.Context) {
+	name, ok := snapshotName(c)
+	if !ok {
+		return
+	}
+
+	state, err := s.loadState(name)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, ErrStateNotFound) {
+			status = http.StatusNotFound
+		}
+		c.AbortWithStatusJSON(status, gin.H{"error": err.Error()})
+		return
+	}
+
+	s.mu.Lock()
+	defer s.unlock()
+
+	s.setState(state)
+	c.JSON(http.StatusOK, s.mock)
+}
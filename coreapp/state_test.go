@@ -0,0 +1,114 @@
+package coreapp
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func serve(s *Server, method, path, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	s.Handler().ServeHTTP(w, req)
+	return w
+}
+
+func TestStateSavedAfterChange(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(Options{Store: store})
+
+	// Journal is changed outside of the request, like the asynchronous webhook goroutines do.
+	s.record(DirectionOutbound, "79001234567", InboundMessage{ID: "a"})
+	s.recordStatus("a", "sent")
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		var state State
+		if err := store.Load(currentState, &state); err == nil && len(state.Journal) == 1 &&
+			state.Journal[0].Status == "sent" {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("state is not saved")
+}
+
+func TestRestoreSnapshot(t *testing.T) {
+	store := NewMemoryStore()
+	mock := DefaultMock()
+	mock.Scenarios = map[string]json.RawMessage{"failing": json.RawMessage(`{"messages_success": false}`)}
+	s := NewServer(Options{Store: store, Mock: &mock})
+
+	if w := serve(s, http.MethodPost, "/mock/scenarios/failing", ""); w.Code != http.StatusOK {
+		t.Fatalf("scenario is not activated: %d", w.Code)
+	}
+	if w := serve(s, http.MethodPost, "/mock/snapshot", `{"name": "failing"}`); w.Code != http.StatusOK {
+		t.Fatalf("snapshot is not saved: %d", w.Code)
+	}
+	serve(s, http.MethodPost, "/mock/scenarios/default", "")
+
+	if w := serve(s, http.MethodPost, "/mock/restore", `{"name": "failing"}`); w.Code != http.StatusOK {
+		t.Fatalf("snapshot is not restored: %d", w.Code)
+	}
+	if s.config().MessagesSuccess {
+		t.Fatal("scenario of the snapshot is not restored")
+	}
+	serve(s, http.MethodPost, "/mock/scenarios/default", "")
+	if !s.config().MessagesSuccess {
+		t.Fatal("initial configuration of the snapshot is not restored")
+	}
+
+	invalid := s.state()
+	invalid.Mock.ContactsStatus = "unknown"
+	if err := store.Save("snapshot-invalid", invalid); err != nil {
+		t.Fatal(err)
+	}
+	if w := serve(s, http.MethodPost, "/mock/restore", `{"name": "invalid"}`); w.Code != http.StatusInternalServerError {
+		t.Fatalf("invalid snapshot is restored: %d", w.Code)
+	}
+}
+
+func TestMediaSavedApart(t *testing.T) {
+	store := NewMemoryStore()
+	s := NewServer(Options{Store: store})
+
+	file := newMediaFile([]byte("media data"), "text/plain")
+	s.mu.Lock()
+	s.media[file.ID] = file
+	s.unlock()
+	if w := serve(s, http.MethodPost, "/mock/snapshot", ""); w.Code != http.StatusOK {
+		t.Fatalf("snapshot is not saved: %d", w.Code)
+	}
+
+	var data []byte
+	if err := store.Load(mediaName(file.ID), &data); err != nil || string(data) != "media data" {
+		t.Fatalf("media is not saved: %q %v", data, err)
+	}
+	if strings.Contains(string(store.data["snapshot-default"]), "media data") {
+		t.Fatal("media data is saved with the state")
+	}
+
+	serve(s, http.MethodDelete, "/v1/media/"+file.ID, "")
+	if w := serve(s, http.MethodPost, "/mock/restore", ""); w.Code != http.StatusOK {
+		t.Fatalf("snapshot is not restored: %d", w.Code)
+	}
+	if restored, ok := s.mediaFile(file.ID); !ok || string(restored.Data) != "media data" {
+		t.Fatalf("media is not restored: %+v", restored)
+	}
+}
+
+func TestNoAutosaveWithoutStore(t *testing.T) {
+	s := NewServer(Options{})
+	s.record(DirectionOutbound, "79001234567", InboundMessage{ID: "a"})
+
+	time.Sleep(2 * saveDelay)
+	var state State
+	if err := s.store.Load(currentState, &state); !errors.Is(err, ErrStateNotFound) {
+		t.Fatalf("state is saved without the store: %v", err)
+	}
+}
@@ -0,0 +1,118 @@
+package coreapp
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+)
+
+var (
+	ErrStateNotFound = errors.New("state not found")
+	stateNameRegex   = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+)
+
+// Store persists named values of the mock state.
+type Store interface {
+	// Load decodes value with the provided name into v. It returns ErrStateNotFound if there is no such value.
+	Load(name string, v interface{}) error
+	Save(name string, v interface{}) error
+}
+
+// MemoryStore keeps the state in memory. It's used when the data directory is not configured.
+type MemoryStore struct {
+	mu   sync.RWMutex
+	data map[string][]byte
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{data: map[string][]byte{}}
+}
+
+func (s *MemoryStore) Load(name string, v interface{}) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	data, ok := s.data[name]
+	if !ok {
+		return ErrStateNotFound
+	}
+	return json.Unmarshal(data, v)
+}
+
+func (s *MemoryStore) Save(name string, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data[name] = data
+	return nil
+}
+
+// FileStore keeps every value as a JSON file in the data directory.
+type FileStore struct {
+	dir string
+}
+
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, err
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(name string) (string, error) {
+	if !stateNameRegex.MatchString(name) {
+		return "", errors.New("invalid state name: " + name)
+	}
+	return filepath.Join(s.dir, name+".json"), nil
+}
+
+func (s *FileStore) Load(name string, v interface{}) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ErrStateNotFound
+	}
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// Save writes the value into a temporary file and renames it, so the state is never partially written.
+func (s *FileStore) Save(name string, v interface{}) error {
+	path, err := s.path(name)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(s.dir, name+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
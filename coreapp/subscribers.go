@@ -0,0 +1,228 @@
+package coreapp
+
+import (
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// defaultRetryDelay is the delay before the first retry, every next retry waits twice as long.
+const defaultRetryDelay = time.Second
+
+const (
+	SubscriberEventMessages = "messages"
+	SubscriberEventStatuses = "statuses"
+	SubscriberEventErrors   = "errors"
+)
+
+// WebhookSubscriber is an additional webhook target which receives the webhooks matching its filters.
+type WebhookSubscriber struct {
+	Name    string            `json:"name" validate:"required"`
+	URL     string            `json:"url" validate:"required,url,startswith=http"`
+	Headers map[string]string `json:"headers,omitempty"`
+	// Events limits delivered items to messages, statuses or errors. Everything is delivered if it's empty.
+	Events []string `json:"events,omitempty" validate:"dive,oneof=messages statuses errors"`
+	// Recipients limits delivered items to the provided WhatsApp IDs. Everything is delivered if it's empty.
+	Recipients []string `json:"recipients,omitempty"`
+	// Retries is the amount of the delivery retries after an error or a non-2xx response.
+	Retries int `json:"retries" validate:"min=0"`
+	// RetryDelayMs is the delay in milliseconds before the first retry, every next retry waits twice as long.
+	RetryDelayMs int `json:"retry_delay_ms" validate:"min=0"`
+}
+
+// SubscriberState is the delivery state of the subscriber.
+type SubscriberState struct {
+	Name string `json:"name"`
+	URL  string `json:"url"`
+	// Delivered and Failed count webhooks which were delivered and the ones which ran out of retries.
+	Delivered int `json:"delivered"`
+	Failed    int `json:"failed"`
+	// Retrying is the amount of webhooks which are waiting for a retry.
+	Retrying  int    `json:"retrying"`
+	LastCode  int    `json:"last_code,omitempty"`
+	LastError string `json:"last_error,omitempty"`
+}
+
+type subscribersState struct {
+	mu     sync.Mutex
+	states map[string]*SubscriberState
+}
+
+func (s WebhookSubscriber) retryDelay() time.Duration {
+	if s.RetryDelayMs == 0 {
+		return defaultRetryDelay
+	}
+	return time.Duration(s.RetryDelayMs) * time.Millisecond
+}
+
+func (s WebhookSubscriber) wants(event, recipient string) bool {
+	return (len(s.Events) == 0 || containsString(s.Events, event)) &&
+		(len(s.Recipients) == 0 || containsString(s.Recipients, recipient))
+}
+
+// filter returns the webhook with the items matching subscriber filters. Errors are not bound to any recipient,
+// so they are delivered to the subscribers without the recipients filter only.
+func (s WebhookSubscriber) filter(webhook InboundWebhook) (InboundWebhook, bool) {
+	var filtered InboundWebhook
+	for _, msg := range webhook.Messages {
+		if s.wants(SubscriberEventMessages, msg.From) {
+			filtered.Messages = append(filtered.Messages, msg)
+		}
+	}
+	for _, status := range webhook.Statuses {
+		if s.wants(SubscriberEventStatuses, status.RecipientID) {
+			filtered.Statuses = append(filtered.Statuses, status)
+		}
+	}
+	if len(s.Recipients) == 0 && (len(s.Events) == 0 || containsString(s.Events, SubscriberEventErrors)) {
+		filtered.Errors = webhook.Errors
+	}
+	for _, contact := range webhook.Contacts {
+		for _, msg := range filtered.Messages {
+			if msg.From == contact.WaID {
+				filtered.Contacts = append(filtered.Contacts, contact)
+				break
+			}
+		}
+	}
+
+	return filtered, len(filtered.Messages)+len(filtered.Statuses)+len(filtered.Errors) > 0
+}
+
+// fanOut delivers the webhook to every subscriber which is interested in it. Subscribers receive nothing until
+// the webhook verification handshake is passed, like the primary webhook.
+func (s *Shooter) fanOut(config ShooterConfig, webhook InboundWebhook, action ChaosAction) {
+	if !config.Verified {
+		return
+	}
+	for _, subscriber := range config.Subscribers {
+		filtered, ok := subscriber.filter(webhook)
+		if !ok {
+			continue
+		}
+
+		wh, err := config.encode(filtered)
+		if err != nil {
+			continue
+		}
+		go s.deliverToSubscriber(subscriber, config.AppSecret, wh, filtered.recipients(), action)
+	}
+}
+
+// fanOutPayload delivers already encoded payload to the subscribers without the events filter.
+func (s *Shooter) fanOutPayload(config ShooterConfig, wh []byte, recipients []string) {
+	if !config.Verified {
+		return
+	}
+	for _, subscriber := range config.Subscribers {
+		if len(subscriber.Events) > 0 {
+			continue
+		}
+		if len(subscriber.Recipients) > 0 && !intersects(subscriber.Recipients, recipients) {
+			continue
+		}
+		go s.deliverToSubscriber(subscriber, config.AppSecret, wh, recipients, "")
+	}
+}
+
+// deliverToSubscriber sends the payload to the subscriber retrying failed attempts with exponential backoff.
+// Every attempt is added to the delivery log.
+func (s *Shooter) deliverToSubscriber(subscriber WebhookSubscriber, secret string, wh []byte, recipients []string,
+	action ChaosAction) {
+	delay := subscriber.retryDelay()
+	for attempt := 1; ; attempt++ {
+		code, err := post(subscriber.URL, subscriber.Headers, secret, wh)
+		if err == nil && (code < 200 || code > 299) {
+			err = fmt.Errorf("webhook responded with code %d", code)
+		}
+		s.log(Delivery{
+			Time:       Now(),
+			URL:        subscriber.URL,
+			Payload:    wh,
+			Code:       code,
+			Error:      errorString(err),
+			Recipients: recipients,
+			Chaos:      action,
+			Subscriber: subscriber.Name,
+			Attempt:    attempt,
+		})
+
+		retry := err != nil && attempt <= subscriber.Retries
+		s.updateSubscriberState(subscriber, attempt, code, err, retry)
+		if !retry {
+			return
+		}
+
+		Sleep(delay)
+		delay *= 2
+	}
+}
+
+func (s *Shooter) updateSubscriberState(subscriber WebhookSubscriber, attempt, code int, err error, retry bool) {
+	s.state.subscribers.mu.Lock()
+	defer s.state.subscribers.mu.Unlock()
+
+	if s.state.subscribers.states == nil {
+		s.state.subscribers.states = map[string]*SubscriberState{}
+	}
+	state, ok := s.state.subscribers.states[subscriber.Name]
+	if !ok {
+		state = &SubscriberState{Name: subscriber.Name}
+		s.state.subscribers.states[subscriber.Name] = state
+	}
+
+	state.URL = subscriber.URL
+	state.LastCode = code
+	state.LastError = errorString(err)
+	if attempt > 1 {
+		state.Retrying--
+	}
+	switch {
+	case retry:
+		state.Retrying++
+	case err != nil:
+		state.Failed++
+	default:
+		state.Delivered++
+	}
+}
+
+// SubscriberStates returns delivery state of the configured subscribers.
+func (s *Shooter) SubscriberStates() []SubscriberState {
+	s.state.subscribers.mu.Lock()
+	defer s.state.subscribers.mu.Unlock()
+
+	subscribers := s.Config().Subscribers
+	states := make([]SubscriberState, 0, len(subscribers))
+	for _, subscriber := range subscribers {
+		state := SubscriberState{Name: subscriber.Name, URL: subscriber.URL}
+		if current, ok := s.state.subscribers.states[subscriber.Name]; ok {
+			state = *current
+		}
+		states = append(states, state)
+	}
+	return states
+}
+
+func (s *Server) subscribersHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, s.shooter.SubscriberStates())
+}
+
+func intersects(a, b []string) bool {
+	for _, item := range a {
+		if containsString(b, item) {
+			return true
+		}
+	}
+	return false
+}
+
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
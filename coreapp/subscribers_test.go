@@ -0,0 +1,79 @@
+package coreapp
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSubscriberFilter(t *testing.T) {
+	webhook := InboundWebhook{
+		Contacts: []InboundContact{{WaID: "79001111111"}, {WaID: "79002222222"}},
+		Messages: []InboundMessage{{From: "79001111111"}, {From: "79002222222"}},
+		Statuses: []InboundStatus{{RecipientID: "79001111111"}},
+		Errors:   []InboundError{{Code: ErrorCodeGenericUser}},
+	}
+
+	tests := []struct {
+		name       string
+		subscriber WebhookSubscriber
+		ok         bool
+		messages   int
+		statuses   int
+		errors     int
+		contacts   int
+	}{
+		{"everything", WebhookSubscriber{}, true, 2, 1, 1, 2},
+		{"statuses", WebhookSubscriber{Events: []string{SubscriberEventStatuses}}, true, 0, 1, 0, 0},
+		{"recipient", WebhookSubscriber{Recipients: []string{"79002222222"}}, true, 1, 0, 0, 1},
+		{"nothing", WebhookSubscriber{Events: []string{SubscriberEventStatuses}, Recipients: []string{"79002222222"}},
+			false, 0, 0, 0, 0},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			filtered, ok := test.subscriber.filter(webhook)
+			if ok != test.ok || len(filtered.Messages) != test.messages || len(filtered.Statuses) != test.statuses ||
+				len(filtered.Errors) != test.errors || len(filtered.Contacts) != test.contacts {
+				t.Fatalf("unexpected webhook: %v %+v", ok, filtered)
+			}
+		})
+	}
+}
+
+func TestSubscriberRetries(t *testing.T) {
+	r := newReceiver(t)
+	r.fail = 2
+	shooter := NewShooter("", nil)
+	shooter.Configure(ShooterConfig{
+		Verified:    true,
+		Subscribers: []WebhookSubscriber{{Name: "crm", URL: r.URL, Retries: 2, RetryDelayMs: 1}},
+	})
+
+	if code, err := shooter.Send(status("a")); code != 0 || err != nil {
+		t.Fatalf("unexpected result without the primary webhook: %d %v", code, err)
+	}
+	r.wait(t, 1)
+
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if states := shooter.SubscriberStates(); states[0].Delivered == 1 {
+			if states[0].Retrying != 0 || states[0].Failed != 0 || len(shooter.Deliveries()) != 3 {
+				t.Fatalf("unexpected state: %+v", states[0])
+			}
+			return
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	t.Fatalf("webhook is not delivered: %+v", shooter.SubscriberStates())
+}
+
+func TestSubscribersRequireVerification(t *testing.T) {
+	r := newReceiver(t)
+	shooter := NewShooter("", nil)
+	shooter.Configure(ShooterConfig{Subscribers: []WebhookSubscriber{{Name: "crm", URL: r.URL}}})
+
+	_, _ = shooter.Send(status("a"))
+	time.Sleep(50 * time.Millisecond)
+	if len(r.received()) != 0 {
+		t.Fatal("webhook is delivered to the subscriber before the verification")
+	}
+}
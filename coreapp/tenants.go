@@ -0,0 +1,193 @@
+package coreapp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Tenant is a virtual Coreapp instance hosted by the same process. Every tenant has its own configuration, account,
+// webhook, journal and state. Requests are routed to the tenant by the host name, path prefix or listen address.
+type Tenant struct {
+	Name string `json:"name" validate:"required"`
+	// Host selects the tenant by the Host header, e.g. shop.coreapp.local.
+	Host string `json:"host,omitempty" validate:"omitempty,hostname_rfc1123"`
+	// PathPrefix selects the tenant by the path prefix, e.g. /shop. The prefix is stripped before routing.
+	PathPrefix string `json:"path_prefix,omitempty" validate:"omitempty,startswith=/"`
+	// Address is the separate address which the tenant listens on, e.g. 0.0.0.0:3003.
+	Address string `json:"address,omitempty" validate:"omitempty,hostname_port"`
+	// Cloud overrides the Cloud API compatibility mode of the process.
+	Cloud *bool `json:"cloud,omitempty"`
+	// Account is the initial WhatsApp account of the tenant.
+	Account *Account `json:"account,omitempty"`
+	// Mock contains the settings which differ from the base mock configuration, like the scenarios do.
+	Mock json.RawMessage `json:"mock,omitempty"`
+}
+
+// LoadTenants returns the tenants from the YAML or JSON file with the "tenants" list.
+func LoadTenants(path string) ([]Tenant, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	if ext := strings.ToLower(filepath.Ext(path)); ext == ".yml" || ext == ".yaml" {
+		if data, err = yamlToJSON(data); err != nil {
+			return nil, fmt.Errorf("cannot parse %s: %w", path, err)
+		}
+	}
+
+	var file struct {
+		Tenants []Tenant `json:"tenants"`
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("cannot parse %s: %w", path, err)
+	}
+
+	names := map[string]bool{}
+	for _, tenant := range file.Tenants {
+		if err := validate.Struct(tenant); err != nil {
+			return nil, fmt.Errorf("invalid tenant %s: %w", tenant.Name, err)
+		}
+		if !stateNameRegex.MatchString(tenant.Name) {
+			return nil, fmt.Errorf("invalid tenant name: %s", tenant.Name)
+		}
+		if names[tenant.Name] {
+			return nil, fmt.Errorf("duplicate tenant name: %s", tenant.Name)
+		}
+		if tenant.Host == "" && tenant.PathPrefix == "" && tenant.Address == "" {
+			return nil, fmt.Errorf("tenant %s must have host, path_prefix or address", tenant.Name)
+		}
+		names[tenant.Name] = true
+	}
+	return file.Tenants, nil
+}
+
+// Options returns the options of the tenant server. Tenant settings are applied on top of the base configuration,
+// the state is kept in the subdirectory of the data directory named after the tenant.
+func (t Tenant) Options(base Mock, cloud bool, dataDir string) (Options, error) {
+	// Base configuration is copied, so the tenant settings don't change its maps.
+	mock, err := copyMock(base)
+	if err != nil {
+		return Options{}, err
+	}
+	if len(t.Mock) > 0 {
+		if err := json.Unmarshal(t.Mock, &mock); err != nil {
+			return Options{}, fmt.Errorf("invalid tenant %s: %w", t.Name, err)
+		}
+	}
+	if err := validate.Struct(mock); err != nil {
+		return Options{}, fmt.Errorf("invalid tenant %s: %w", t.Name, err)
+	}
+
+	opts := Options{
+		Cloud:   cloud,
+		Mock:    &mock,
+		Account: t.Account,
+	}
+	if t.Cloud != nil {
+		opts.Cloud = *t.Cloud
+	}
+	if dataDir != "" {
+		if opts.Store, err = NewFileStore(filepath.Join(dataDir, t.Name)); err != nil {
+			return Options{}, err
+		}
+	}
+	return opts, nil
+}
+
+type tenantServer struct {
+	Tenant
+	server *Server
+}
+
+// TenantRouter routes requests to the tenant servers. Requests which don't match any tenant are served
+// by the default server.
+type TenantRouter struct {
+	fallback *Server
+	tenants  []tenantServer
+}
+
+func NewTenantRouter(fallback *Server) *TenantRouter {
+	return &TenantRouter{fallback: fallback}
+}
+
+func (r *TenantRouter) Add(tenant Tenant, server *Server) {
+	r.tenants = append(r.tenants, tenantServer{Tenant: tenant, server: server})
+}
+
+// match returns the tenant server for the request. Host name takes precedence over the longest path prefix.
+func (r *TenantRouter) match(req *http.Request) (tenantServer, bool) {
+	host, _, err := net.SplitHostPort(req.Host)
+	if err != nil {
+		host = req.Host
+	}
+
+	var matched tenantServer
+	for _, tenant := range r.tenants {
+		if tenant.Host != "" && strings.EqualFold(tenant.Host, host) {
+			return tenant, true
+		}
+		if tenant.PathPrefix != "" && len(tenant.PathPrefix) > len(matched.PathPrefix) &&
+			(req.URL.Path == tenant.PathPrefix || strings.HasPrefix(req.URL.Path, strings.TrimSuffix(tenant.PathPrefix, "/")+"/")) {
+			matched = tenant
+		}
+	}
+	return matched, matched.server != nil
+}
+
+func (r *TenantRouter) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodGet && req.URL.Path == "/mock/tenants" {
+		r.tenantsHandler(w)
+		return
+	}
+
+	tenant, ok := r.match(req)
+	if !ok {
+		r.fallback.Handler().ServeHTTP(w, req)
+		return
+	}
+
+	host, _, err := net.SplitHostPort(req.Host)
+	if err != nil {
+		host = req.Host
+	}
+	if tenant.PathPrefix == "" || strings.EqualFold(tenant.Host, host) {
+		tenant.server.Handler().ServeHTTP(w, req)
+		return
+	}
+	http.StripPrefix(strings.TrimSuffix(tenant.PathPrefix, "/"), tenant.server.Handler()).ServeHTTP(w, req)
+}
+
+// tenantsHandler returns the configured tenants.
+func (r *TenantRouter) tenantsHandler(w http.ResponseWriter) {
+	tenants := make([]Tenant, 0, len(r.tenants))
+	for _, tenant := range r.tenants {
+		tenants = append(tenants, tenant.Tenant)
+	}
+
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	_ = json.NewEncoder(w).Encode(tenants)
+}
+
+// Run serves the default server and the tenants on the address. Tenants with their own address
+// are served on it as well.
+func (r *TenantRouter) Run(addr string) error {
+	errs := make(chan error, len(r.tenants)+1)
+	for _, tenant := range r.tenants {
+		if tenant.Address == "" {
+			continue
+		}
+		go func(tenant tenantServer) {
+			errs <- fmt.Errorf("tenant %s: %w", tenant.Name, http.ListenAndServe(tenant.Address, tenant.server.Handler()))
+		}(tenant)
+	}
+	go func() {
+		errs <- http.ListenAndServe(addr, r)
+	}()
+	return <-errs
+}
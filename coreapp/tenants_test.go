@@ -0,0 +1,73 @@
+package coreapp
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func tenantTestServer(webhook string) *Server {
+	mock := DefaultMock()
+	mock.Webhook = webhook
+	return NewServer(Options{Mock: &mock})
+}
+
+func newTestTenantRouter() *TenantRouter {
+	router := NewTenantRouter(tenantTestServer("http://fallback"))
+	router.Add(Tenant{Name: "shop", Host: "shop.coreapp.local"}, tenantTestServer("http://shop"))
+	router.Add(Tenant{Name: "support", PathPrefix: "/support"}, tenantTestServer("http://support"))
+	router.Add(Tenant{Name: "vip", PathPrefix: "/support/vip/"}, tenantTestServer("http://vip"))
+	return router
+}
+
+func TestTenantRouterMatch(t *testing.T) {
+	router := newTestTenantRouter()
+	tests := []struct {
+		host   string
+		path   string
+		tenant string
+	}{
+		{"localhost:3002", "/mock", ""},
+		{"shop.coreapp.local", "/mock", "shop"},
+		{"SHOP.coreapp.local:3002", "/mock", "shop"},
+		{"shop.coreapp.local", "/support/mock", "shop"},
+		{"localhost", "/support", "support"},
+		{"localhost", "/support/mock", "support"},
+		{"localhost", "/support/vip/mock", "vip"},
+		{"localhost", "/supportx/mock", ""},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		req.Host = test.host
+		tenant, ok := router.match(req)
+		if ok != (test.tenant != "") || tenant.Name != test.tenant {
+			t.Fatalf("%s%s is routed to %q instead of %q", test.host, test.path, tenant.Name, test.tenant)
+		}
+	}
+}
+
+func TestTenantRouterServe(t *testing.T) {
+	router := newTestTenantRouter()
+	tests := []struct {
+		host    string
+		path    string
+		webhook string
+	}{
+		{"localhost", "/mock", "http://fallback"},
+		{"shop.coreapp.local", "/mock", "http://shop"},
+		{"localhost", "/support/mock", "http://support"},
+		{"localhost", "/support/vip/mock", "http://vip"},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(http.MethodGet, test.path, nil)
+		req.Host = test.host
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+
+		var mock Mock
+		if err := json.Unmarshal(w.Body.Bytes(), &mock); err != nil || mock.Webhook != test.webhook {
+			t.Fatalf("%s%s is served by %q instead of %q: %d", test.host, test.path, mock.Webhook, test.webhook, w.Code)
+		}
+	}
+}
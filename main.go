@@ -1,18 +1,31 @@
 package main
 
 import (
+	"errors"
 	"net/http"
+	"net/url"
 	"os"
 	"time"
 
+	"github.com/Neur0toxine/waba-coreapp-mock/coreapp"
 	"github.com/gin-gonic/gin"
+	"github.com/labstack/gommon/log"
 	"github.com/mkideal/cli"
 )
 
 type CLI struct {
 	cli.Helper2
-	Address string `cli:"*addr,address" usage:"Address to listen"`
-	Verbose bool   `cli:"v,verbose" usage:"Enable verbose logging"`
+	Address  string `cli:"*addr,address" usage:"Address to listen"`
+	Verbose  bool   `cli:"v,verbose" usage:"Enable verbose logging"`
+	Cloud    bool   `cli:"cloud" usage:"Enable Cloud API compatibility mode"`
+	Config   string `cli:"c,config" usage:"Path to YAML or JSON mock configuration file"`
+	DataDir  string `cli:"data-dir" usage:"Directory to persist the state between restarts"`
+	Record   string `cli:"record" usage:"Append API calls and webhook deliveries to the JSONL file"`
+	Replay   string `cli:"replay" usage:"Replay webhooks from the JSONL recording after start"`
+	Upstream string `cli:"upstream" usage:"Forward API requests to the Coreapp at this URL"`
+	Fixtures string `cli:"fixtures" usage:"Serve recorded API responses from the JSONL recording"`
+	Seed     int64  `cli:"seed" usage:"Seed for the generated IDs, also enables the virtual clock"`
+	Tenants  string `cli:"tenants" usage:"Path to YAML or JSON file with the virtual Coreapp instances"`
 }
 
 func main() {
@@ -26,6 +39,83 @@ func main() {
 
 		http.DefaultClient.Timeout = time.Second * 30
 
-		return NewServer().Run(argv.Address)
+		if argv.Tenants != "" && (argv.Record != "" || argv.Replay != "" || argv.Upstream != "" || argv.Fixtures != "") {
+			return errors.New("--record, --replay, --upstream and --fixtures cannot be used with --tenants")
+		}
+
+		if ctx.IsSet("--seed") {
+			coreapp.SetSeed(argv.Seed)
+			coreapp.UseVirtualClock(coreapp.VirtualEpoch)
+		}
+
+		mock, err := coreapp.LoadMock(argv.Config)
+		if err != nil {
+			return err
+		}
+
+		opts := coreapp.Options{
+			Cloud: argv.Cloud,
+			Mock:  &mock,
+		}
+		if argv.DataDir != "" {
+			if opts.Store, err = coreapp.NewFileStore(argv.DataDir); err != nil {
+				return err
+			}
+		}
+
+		if argv.Record != "" {
+			if opts.Recorder, err = coreapp.NewRecorder(argv.Record); err != nil {
+				return err
+			}
+			defer opts.Recorder.Close()
+		}
+
+		if argv.Upstream != "" {
+			if opts.Upstream, err = url.Parse(argv.Upstream); err != nil {
+				return err
+			}
+		}
+		if argv.Fixtures != "" {
+			if opts.Fixtures, err = coreapp.LoadFixtures(argv.Fixtures); err != nil {
+				return err
+			}
+		}
+
+		server := coreapp.NewServer(opts)
+		if err := server.LoadState(); err != nil {
+			return err
+		}
+
+		if argv.Replay != "" {
+			go func() {
+				if err := server.ReplayFile(argv.Replay); err != nil {
+					log.Printf("replay error: %s\n", err)
+				}
+			}()
+		}
+
+		if argv.Tenants == "" {
+			return server.Run(argv.Address)
+		}
+
+		tenants, err := coreapp.LoadTenants(argv.Tenants)
+		if err != nil {
+			return err
+		}
+
+		router := coreapp.NewTenantRouter(server)
+		for _, tenant := range tenants {
+			tenantOpts, err := tenant.Options(mock, argv.Cloud, argv.DataDir)
+			if err != nil {
+				return err
+			}
+
+			tenantServer := coreapp.NewServer(tenantOpts)
+			if err := tenantServer.LoadState(); err != nil {
+				return err
+			}
+			router.Add(tenant, tenantServer)
+		}
+		return router.Run(argv.Address)
 	}))
 }